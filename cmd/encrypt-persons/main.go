@@ -0,0 +1,241 @@
+// encrypt-persons 一次性迁移：加密已有的身份证号、电话等敏感字段并生成盲索引，以及用户的 TOTP 密钥
+//
+// 先执行 scripts/alter_person_encrypt_sensitive.sql、scripts/alter_sys_user_encrypt_totp_secret.sql，
+// 再使用与服务相同的加密密钥运行：
+//
+//	go run ./cmd/encrypt-persons [-dry-run] [-batch 500]
+//
+// 已加密的数据会跳过，可重复执行。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+
+	"PLMS/internal/config"
+	"PLMS/internal/database"
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// personRow person 表中需要加密的原始列（按表名查询，不经过序列化器）
+type personRow struct {
+	ID                int64
+	IDCard            string `gorm:"column:id_card"`
+	IDCardBidx        string `gorm:"column:id_card_bidx"`
+	Telephone         string `gorm:"column:telephone"`
+	TelephoneBidx     string `gorm:"column:telephone_bidx"`
+	ElderContactPhone string `gorm:"column:elder_contact_phone"`
+}
+
+// changeRow import_batch_change 中的回滚数据
+type changeRow struct {
+	ID        int64
+	OldValues string `gorm:"column:old_values"`
+}
+
+// userRow sys_user 中的 TOTP 密钥
+type userRow struct {
+	ID         int64
+	TotpSecret string `gorm:"column:totp_secret"`
+}
+
+// auditRow audit_log 中的查询条件及修改前后数据
+type auditRow struct {
+	ID         int64
+	Filter     string `gorm:"column:filter"`
+	BeforeData string `gorm:"column:before_data"`
+	AfterData  string `gorm:"column:after_data"`
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只统计需要加密的记录，不写入数据库")
+	batchSize := flag.Int("batch", 500, "每批处理的记录数")
+	flag.Parse()
+
+	// 需使用与服务相同的加密密钥
+	cfg := config.LoadConfig()
+	if err := fieldcrypt.Configure(cfg.Crypto.FieldCrypt()); err != nil {
+		log.Fatal("加密密钥配置错误:", err)
+	}
+
+	// 初始化数据库连接
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		log.Fatal("数据库连接失败:", err)
+	}
+
+	m := &migrator{db: db, dryRun: *dryRun, batchSize: *batchSize}
+	steps := []struct {
+		name string
+		run  func() (int, error)
+	}{
+		{"person", m.encryptPersons},
+		{"import_batch_change", m.encryptImportChanges},
+		{"audit_log", m.encryptAuditLogs},
+		{"sys_user", m.encryptTotpSecrets},
+	}
+	for _, step := range steps {
+		count, err := step.run()
+		if err != nil {
+			log.Fatalf("%s 加密失败: %v", step.name, err)
+		}
+		if m.dryRun {
+			log.Printf("%s: %d 条记录需要加密", step.name, count)
+		} else {
+			log.Printf("%s: 已加密 %d 条记录", step.name, count)
+		}
+	}
+}
+
+// migrator 按主键分批加密各表数据
+type migrator struct {
+	db        *gorm.DB
+	dryRun    bool
+	batchSize int
+}
+
+// eachBatch 按主键顺序分批读取表数据，handle 返回需要更新的字段（为空表示无需更新）
+func eachBatch[T any](m *migrator, table, columns string, id func(*T) int64, handle func(*T) (map[string]interface{}, error)) (int, error) {
+	var lastID int64
+	count := 0
+	for {
+		var rows []T
+		if err := m.db.Table(table).Select(columns).Where("id > ?", lastID).
+			Order("id").Limit(m.batchSize).Find(&rows).Error; err != nil {
+			return count, err
+		}
+		if len(rows) == 0 {
+			return count, nil
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			for i := range rows {
+				updates, err := handle(&rows[i])
+				if err != nil {
+					return err
+				}
+				if len(updates) == 0 {
+					continue
+				}
+				count++
+				if m.dryRun {
+					continue
+				}
+				if err := tx.Table(table).Where("id = ?", id(&rows[i])).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		lastID = id(&rows[len(rows)-1])
+	}
+}
+
+// encryptPersons 加密人员的身份证号、电话、紧急联系电话，并补充盲索引
+func (m *migrator) encryptPersons() (int, error) {
+	return eachBatch(m, "person", "id, COALESCE(id_card, '') AS id_card, id_card_bidx, "+
+		"COALESCE(telephone, '') AS telephone, telephone_bidx, COALESCE(elder_contact_phone, '') AS elder_contact_phone",
+		func(row *personRow) int64 { return row.ID },
+		personUpdates)
+}
+
+// personUpdates 人员记录需要更新的字段：加密明文并补充缺失的盲索引，已加密且有盲索引的记录返回空
+func personUpdates(row *personRow) (map[string]interface{}, error) {
+	values := map[string]interface{}{
+		"id_card":             row.IDCard,
+		"telephone":           row.Telephone,
+		"elder_contact_phone": row.ElderContactPhone,
+	}
+	if err := models.EncryptPersonValues(values); err != nil {
+		return nil, err
+	}
+	current := map[string]string{
+		"id_card":             row.IDCard,
+		"id_card_bidx":        row.IDCardBidx,
+		"telephone":           row.Telephone,
+		"telephone_bidx":      row.TelephoneBidx,
+		"elder_contact_phone": row.ElderContactPhone,
+	}
+	// 已加密的值加密后保持原密文，只更新明文及缺失的盲索引
+	for column, value := range values {
+		if value == current[column] {
+			delete(values, column)
+		}
+	}
+	return values, nil
+}
+
+// encryptImportChanges 加密导入批次回滚记录中保存的敏感字段原值
+func (m *migrator) encryptImportChanges() (int, error) {
+	return eachBatch(m, "import_batch_change", "id, COALESCE(old_values, '') AS old_values",
+		func(row *changeRow) int64 { return row.ID },
+		func(row *changeRow) (map[string]interface{}, error) {
+			if row.OldValues == "" {
+				return nil, nil
+			}
+			var oldValues map[string]interface{}
+			if err := json.Unmarshal([]byte(row.OldValues), &oldValues); err != nil {
+				log.Printf("import_batch_change %d 的原始数据无法解析，已跳过: %v", row.ID, err)
+				return nil, nil
+			}
+			if err := models.EncryptPersonValues(oldValues); err != nil {
+				return nil, err
+			}
+			encoded, err := json.Marshal(oldValues)
+			if err != nil {
+				return nil, err
+			}
+			if string(encoded) == row.OldValues {
+				return nil, nil
+			}
+			return map[string]interface{}{"old_values": string(encoded)}, nil
+		})
+}
+
+// encryptAuditLogs 加密审计日志的查询条件及修改前后数据
+func (m *migrator) encryptAuditLogs() (int, error) {
+	return eachBatch(m, "audit_log", "id, COALESCE(filter, '') AS filter, "+
+		"COALESCE(before_data, '') AS before_data, COALESCE(after_data, '') AS after_data",
+		func(row *auditRow) int64 { return row.ID },
+		func(row *auditRow) (map[string]interface{}, error) {
+			updates := make(map[string]interface{})
+			for column, value := range map[string]string{
+				"filter":      row.Filter,
+				"before_data": row.BeforeData,
+				"after_data":  row.AfterData,
+			} {
+				if value == "" || fieldcrypt.IsEncrypted(value) {
+					continue
+				}
+				encrypted, err := fieldcrypt.Encrypt(value)
+				if err != nil {
+					return nil, err
+				}
+				updates[column] = encrypted
+			}
+			return updates, nil
+		})
+}
+
+// encryptTotpSecrets 加密用户的 TOTP 密钥
+func (m *migrator) encryptTotpSecrets() (int, error) {
+	return eachBatch(m, "sys_user", "id, COALESCE(totp_secret, '') AS totp_secret",
+		func(row *userRow) int64 { return row.ID },
+		func(row *userRow) (map[string]interface{}, error) {
+			if row.TotpSecret == "" || fieldcrypt.IsEncrypted(row.TotpSecret) {
+				return nil, nil
+			}
+			encrypted, err := fieldcrypt.Encrypt(row.TotpSecret)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"totp_secret": encrypted}, nil
+		})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"PLMS/internal/fieldcrypt"
+)
+
+func TestPersonUpdates(t *testing.T) {
+	if err := fieldcrypt.Configure(fieldcrypt.Config{AllowDevKey: true}); err != nil {
+		t.Fatal(err)
+	}
+	encryptedIDCard, _ := fieldcrypt.Encrypt("11010519491231002X")
+	encryptedPhone, _ := fieldcrypt.Encrypt("13812345678")
+
+	tests := []struct {
+		name        string
+		row         personRow
+		wantColumns []string
+	}{
+		{
+			name:        "明文数据",
+			row:         personRow{IDCard: "11010519491231002X", Telephone: "13812345678", ElderContactPhone: "13987654321"},
+			wantColumns: []string{"id_card", "id_card_bidx", "telephone", "telephone_bidx", "elder_contact_phone"},
+		},
+		{
+			name:        "已加密但缺少盲索引",
+			row:         personRow{IDCard: encryptedIDCard, Telephone: encryptedPhone},
+			wantColumns: []string{"id_card_bidx", "telephone_bidx"},
+		},
+		{
+			name: "已加密且有盲索引",
+			row: personRow{
+				IDCard:        encryptedIDCard,
+				IDCardBidx:    fieldcrypt.BlindIndex("11010519491231002X"),
+				Telephone:     encryptedPhone,
+				TelephoneBidx: fieldcrypt.BlindIndex("13812345678"),
+			},
+		},
+		{
+			name: "空值",
+			row:  personRow{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, err := personUpdates(&tt.row)
+			if err != nil {
+				t.Fatalf("personUpdates 返回错误: %v", err)
+			}
+			if len(updates) != len(tt.wantColumns) {
+				t.Errorf("更新字段 = %v, want %v", updates, tt.wantColumns)
+			}
+			for _, column := range tt.wantColumns {
+				if _, ok := updates[column]; !ok {
+					t.Errorf("缺少更新字段 %s", column)
+				}
+			}
+
+			// 写入更新后再次执行，不应再有需要更新的字段
+			row := tt.row
+			for column, value := range updates {
+				str := value.(string)
+				switch column {
+				case "id_card":
+					row.IDCard = str
+				case "id_card_bidx":
+					row.IDCardBidx = str
+				case "telephone":
+					row.Telephone = str
+				case "telephone_bidx":
+					row.TelephoneBidx = str
+				case "elder_contact_phone":
+					row.ElderContactPhone = str
+				}
+			}
+			again, err := personUpdates(&row)
+			if err != nil {
+				t.Fatalf("再次执行 personUpdates 返回错误: %v", err)
+			}
+			if len(again) != 0 {
+				t.Errorf("再次执行仍需更新 %v", again)
+			}
+			if row.IDCard != "" && row.IDCardBidx != fieldcrypt.BlindIndex("11010519491231002X") {
+				t.Errorf("身份证号盲索引 = %q，与明文的盲索引不一致", row.IDCardBidx)
+			}
+		})
+	}
+}
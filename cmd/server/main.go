@@ -9,20 +9,38 @@ import (
 
 	"PLMS/internal/config"
 	"PLMS/internal/database"
+	"PLMS/internal/fieldcrypt"
 	"PLMS/internal/handlers"
 	"PLMS/internal/middleware"
+	"PLMS/internal/models"
+	"PLMS/internal/services"
 )
 
 func main() {
 	// 加载配置
 	cfg := config.LoadConfig()
 
+	// 敏感字段加密密钥（除开发环境外必须配置）
+	if err := fieldcrypt.Configure(cfg.Crypto.FieldCrypt()); err != nil {
+		log.Fatal("加密密钥配置错误:", err)
+	}
+
 	// 初始化数据库连接
 	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatal("数据库连接失败:", err)
 	}
 
+	// token 有效期、登录限制及密码策略
+	services.ConfigureAuth(cfg.Auth)
+	services.ConfigurePasswordPolicy(cfg.Password)
+
+	// 为已有人员按身份证号补充出生日期（年龄按出生日期计算）
+	services.StartBirthDateBackfill(db)
+
+	// 后台导入任务（上次退出时未完成的任务标记为失败）
+	services.StartImportWorker(db)
+
 	// 设置 Gin 模式
 	if cfg.App.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -68,26 +86,57 @@ func setupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		{
 			// 登录接口 - 无需认证
 			api.POST("/auth/login", authHandler.Login)
+			// 刷新token - 无需认证（使用刷新token）
+			api.POST("/auth/refresh", authHandler.RefreshToken)
+			// 双因素认证第二步 - 无需认证（使用待验证token）
+			api.POST("/auth/login/2fa", authHandler.VerifyTwoFactorLogin)
 		}
 
 		// ==================== 需要认证的路由 ====================
 		// 使用认证中间件
 		authorized := api.Group("")
-		authorized.Use(middleware.AuthMiddleware())
+		authorized.Use(middleware.AuthMiddleware(db))
 		// 添加默认密码检查中间件
 		authorized.Use(middleware.DefaultPasswordCheckMiddleware(db))
+		// 加载用户权限及楼栋范围
+		authorized.Use(middleware.AccessMiddleware(db))
+		// 要求管理员启用双因素认证时，未启用的管理员只能先完成绑定
+		authorized.Use(middleware.TwoFactorSetupMiddleware(db))
 		{
 			// 认证相关 - 需要登录
 			authorized.GET("/auth/current-user", authHandler.GetCurrentUser)
 			authorized.POST("/auth/change-password", authHandler.ChangePassword)
 			authorized.POST("/auth/logout", authHandler.Logout)
 			authorized.GET("/auth/check-default-password", authHandler.CheckDefaultPassword)
+			authorized.POST("/auth/2fa/setup", authHandler.SetupTwoFactor)
+			authorized.POST("/auth/2fa/enable", authHandler.EnableTwoFactor)
+			authorized.POST("/auth/2fa/disable", authHandler.DisableTwoFactor)
+			authorized.POST("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			authorized.GET("/auth/sessions", authHandler.GetSessions)
+			authorized.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+			accessHandler := handlers.NewAccessHandler(db)
+			authorized.GET("/auth/access", accessHandler.GetCurrentAccess)
+
+			// 管理员接口
+			admin := authorized.Group("")
+			admin.Use(middleware.RequireRole(models.RoleAdmin))
 
 			// 用户管理接口（需要管理员权限）
-			authorized.GET("/auth/users", authHandler.GetUserList)
-			authorized.POST("/auth/users", authHandler.CreateUser)
-			authorized.PUT("/auth/users/:id", authHandler.UpdateUser)
-			authorized.DELETE("/auth/users/:id", authHandler.DeleteUser)
+			admin.GET("/auth/users", authHandler.GetUserList)
+			admin.POST("/auth/users", authHandler.CreateUser)
+			admin.PUT("/auth/users/:id", authHandler.UpdateUser)
+			admin.DELETE("/auth/users/:id", authHandler.DeleteUser)
+			admin.POST("/auth/users/:id/logout", authHandler.ForceLogout)
+			admin.POST("/auth/users/:id/unlock", authHandler.UnlockUser)
+			admin.POST("/auth/users/:id/2fa/reset", authHandler.ResetTwoFactor)
+			admin.GET("/auth/user-sessions", authHandler.GetSessionList)
+			admin.DELETE("/auth/user-sessions/:id", authHandler.AdminRevokeSession)
+			admin.GET("/auth/users/:id/buildings", accessHandler.GetUserBuildings)
+			admin.PUT("/auth/users/:id/buildings", accessHandler.SetUserBuildings)
+			admin.GET("/auth/roles", accessHandler.GetRoles)
+			admin.PUT("/auth/roles/:code", accessHandler.SaveRole)
+			auditHandler := handlers.NewAuditHandler(db)
+			admin.GET("/audit/logs", auditHandler.GetAuditLogs)
 
 			// 用户相关路由 - 需要登录（原接口，保留兼容）
 			//userHandler := handlers.NewUserHandler(db)
@@ -98,23 +147,78 @@ func setupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			//authorized.PUT("/users/:id", userHandler.UpdateUser)
 			//authorized.DELETE("/users/:id", userHandler.DeleteUser)
 
-			// 住户相关api - 需要登录
+			// 审计日志 - 住户数据的查看、导出、修改均记录操作人及涉及的人员
+			audit := func(resource, action string) gin.HandlerFunc {
+				return middleware.Audit(db, resource, action)
+			}
+
+			// 编辑接口 - 需要编辑权限
+			editor := authorized.Group("")
+			editor.Use(middleware.RequirePermission(models.PermEdit))
+
+			// 住户相关api - 需要登录，查询结果限制在用户的楼栋范围内
 			personHandler := handlers.NewPersonHandler(db)
 			authorized.GET("/getBuildingNumbers", personHandler.GetBuildingNumbers)
 			authorized.GET("/getUnitNumbersByBuildingNumber", personHandler.GetUnitNumbersByBuildingNumber)
-			authorized.POST("/getPersons", personHandler.GetPersons)
+			authorized.POST("/getPersons", audit(models.AuditResourcePerson, models.AuditActionList), personHandler.GetPersons)
 			authorized.GET("/getPersonStatistics", personHandler.GetPersonStatistics)
-			authorized.POST("/getRooms", personHandler.GetRooms)
-			authorized.GET("/getPersonInfo", personHandler.GetPersonInfo)
-			authorized.GET("/getPersonInfoByRoom", personHandler.GetPersonInfoByRoom)
-
-			// 导出接口 - 需要登录
+			authorized.POST("/getRooms", audit(models.AuditResourcePerson, models.AuditActionList), personHandler.GetRooms)
+			authorized.GET("/getPersonInfo", audit(models.AuditResourcePerson, models.AuditActionView), personHandler.GetPersonInfo)
+			authorized.GET("/getPersonInfoByRoom", audit(models.AuditResourcePerson, models.AuditActionView), personHandler.GetPersonInfoByRoom)
+			editor.POST("/persons", audit(models.AuditResourcePerson, models.AuditActionCreate), personHandler.CreatePerson)
+			editor.PUT("/persons/:id", audit(models.AuditResourcePerson, models.AuditActionUpdate), personHandler.UpdatePerson)
+			editor.DELETE("/persons/:id", audit(models.AuditResourcePerson, models.AuditActionDelete), personHandler.DeletePerson)
+			// 查看敏感信息明文 - 需要查看敏感信息权限，每次查看均记录审计日志
+			authorized.GET("/persons/:id/sensitive", middleware.RequirePermission(models.PermViewSensitive),
+				audit(models.AuditResourcePerson, models.AuditActionReveal), personHandler.RevealSensitive)
+
+			// 重复人员检查及合并 - 仅管理员，合并记录审计日志
+			admin.GET("/persons/duplicates", audit(models.AuditResourcePerson, models.AuditActionList), personHandler.GetDuplicates)
+			admin.POST("/persons/merge", audit(models.AuditResourcePerson, models.AuditActionMerge), personHandler.MergePersons)
+			admin.GET("/persons/merges", personHandler.GetPersonMerges)
+
+			// 电动车相关api - 需要登录
+			bicycleHandler := handlers.NewElectricBicycleHandler(db)
+			authorized.GET("/persons/:id/bicycles", audit(models.AuditResourceBicycle, models.AuditActionView), bicycleHandler.GetBicycles)
+			editor.POST("/persons/:id/bicycles", audit(models.AuditResourceBicycle, models.AuditActionCreate), bicycleHandler.CreateBicycle)
+			editor.PUT("/bicycles/:id", audit(models.AuditResourceBicycle, models.AuditActionUpdate), bicycleHandler.UpdateBicycle)
+			editor.DELETE("/bicycles/:id", audit(models.AuditResourceBicycle, models.AuditActionDelete), bicycleHandler.DeleteBicycle)
+			authorized.GET("/bicycles/lookup", audit(models.AuditResourceBicycle, models.AuditActionList), bicycleHandler.FindByPlateNumber)
+
+			// 楼栋、单元、房间基础数据 - 查询需要登录，维护需要编辑权限（没有住户的房间同样可以维护）
+			housingHandler := handlers.NewHousingHandler(db)
+			authorized.GET("/buildings", housingHandler.GetBuildings)
+			editor.POST("/buildings", audit(models.AuditResourceHousing, models.AuditActionCreate), housingHandler.CreateBuilding)
+			editor.PUT("/buildings/:id", audit(models.AuditResourceHousing, models.AuditActionUpdate), housingHandler.UpdateBuilding)
+			editor.DELETE("/buildings/:id", audit(models.AuditResourceHousing, models.AuditActionDelete), housingHandler.DeleteBuilding)
+			authorized.GET("/buildings/:id/units", housingHandler.GetUnits)
+			editor.POST("/buildings/:id/units", audit(models.AuditResourceHousing, models.AuditActionCreate), housingHandler.CreateUnit)
+			editor.DELETE("/units/:id", audit(models.AuditResourceHousing, models.AuditActionDelete), housingHandler.DeleteUnit)
+			authorized.GET("/units/:id/rooms", housingHandler.GetRooms)
+			editor.POST("/units/:id/rooms", audit(models.AuditResourceHousing, models.AuditActionCreate), housingHandler.CreateRoom)
+			editor.PUT("/rooms/:id", audit(models.AuditResourceHousing, models.AuditActionUpdate), housingHandler.UpdateRoom)
+			editor.DELETE("/rooms/:id", audit(models.AuditResourceHousing, models.AuditActionDelete), housingHandler.DeleteRoom)
+
+			// 数据质量 - 按楼栋统计缺失、无效及不一致的数据，查看明细时记录审计日志
+			qualityHandler := handlers.NewQualityHandler(db)
+			authorized.GET("/quality", qualityHandler.GetReport)
+			authorized.GET("/quality/:issue", audit(models.AuditResourcePerson, models.AuditActionList), qualityHandler.GetIssueDetails)
+
+			// 导出接口 - 需要导出权限
 			authorized.GET("/exportFields", personHandler.GetExportFields)
-			authorized.POST("/exportPersons", personHandler.ExportPersons)
-
-			// Excel导入接口 - 需要登录（管理员权限）
-			updateHandler := handlers.NewUpdateExecDataHandler(db)
-			authorized.POST("/import/excel", updateHandler.ImportExcel)
+			authorized.POST("/exportPersons", middleware.RequirePermission(models.PermExport), audit(models.AuditResourcePerson, models.AuditActionExport), personHandler.ExportPersons)
+
+			// Excel导入接口 - 需要导入权限
+			importer := authorized.Group("")
+			importer.Use(middleware.RequirePermission(models.PermImport))
+			updateHandler := handlers.NewUpdateExecDataHandler(db, cfg.Import)
+			importer.POST("/import/excel", audit(models.AuditResourceImport, models.AuditActionImport), updateHandler.ImportExcel)
+			importer.POST("/import/commit", audit(models.AuditResourceImport, models.AuditActionImport), updateHandler.CommitImport)
+			importer.POST("/import/batches/:id/rollback", audit(models.AuditResourceImport, models.AuditActionImport), updateHandler.RollbackImportBatch)
+			importer.GET("/import/profiles", updateHandler.GetImportProfiles)
+			// 导入历史：管理员可查看全部任务，其他用户只能查看自己提交的任务
+			importer.GET("/import/jobs", updateHandler.GetImportJobList)
+			importer.GET("/import/jobs/:id", updateHandler.GetImportJob)
 		}
 	}
 
@@ -3,12 +3,16 @@ package main
 import (
 	"PLMS/internal/config"
 	"PLMS/internal/database"
+	"PLMS/internal/fieldcrypt"
 	"PLMS/internal/handlers"
 	"log"
 )
 
 func main() {
 	cfg := config.LoadConfig()
+	if err := fieldcrypt.Configure(cfg.Crypto.FieldCrypt()); err != nil {
+		log.Fatal("加密密钥配置错误:", err)
+	}
 
 	// 初始化数据库连接
 	db, err := database.InitDB(cfg.Database)
@@ -16,12 +20,13 @@ func main() {
 		log.Fatal("数据库连接失败:", err)
 	}
 	//人口台账
-	handler := handlers.NewUpdateExecDataHandler(db)
+	handler := handlers.NewUpdateExecDataHandler(db, cfg.Import)
 	result, err := handler.UpdateExecData("/Users/wangyao/GolandProjects/PLMS/web/alldata.xlsx")
 	if err != nil {
 		log.Fatal("导入失败:", err)
 	}
-	log.Printf("导入完成: %d 个工作表, %d 条数据", result.TotalSheets, result.TotalPersons)
+	log.Printf("导入完成: %d 个工作表, %d 条数据（新增 %d，更新 %d，未变化 %d）",
+		result.TotalSheets, result.TotalPersons, result.Inserted, result.Updated, result.Unchanged)
 	for _, detail := range result.Details {
 		log.Println(detail)
 	}
@@ -3,11 +3,18 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
+
+	"PLMS/internal/fieldcrypt"
 )
 
 type Config struct {
 	App      AppConfig
 	Database DatabaseConfig
+	Import   ImportConfig
+	Auth     AuthConfig
+	Password PasswordConfig
+	Crypto   CryptoConfig
 }
 
 type AppConfig struct {
@@ -26,6 +33,42 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+type ImportConfig struct {
+	ProfileDir string // 导入方案（JSON）目录
+}
+
+type AuthConfig struct {
+	AccessTokenTTL   time.Duration // 访问token有效期
+	RefreshTokenTTL  time.Duration // 刷新token有效期
+	MaxLoginFailures int           // 账号连续登录失败多少次后锁定
+	LockDuration     time.Duration // 账号锁定时长
+	MaxIPFailures    int           // 同一IP在统计窗口内允许的登录失败次数
+	IPFailureWindow  time.Duration // IP登录失败统计窗口
+	RequireAdmin2FA  bool          // 是否要求管理员启用双因素认证
+}
+
+type PasswordConfig struct {
+	MinLength      int // 最小长度
+	MinCharClasses int // 至少包含的字符类别数（大写字母、小写字母、数字、符号）
+	HistorySize    int // 不得与最近几次使用过的密码相同
+	MaxAgeDays     int // 密码有效期（天），0 表示永不过期
+}
+
+type CryptoConfig struct {
+	EncryptionKey string // 敏感字段加密密钥（base64 编码的 32 字节）
+	BlindIndexKey string // 盲索引密钥（base64 编码，为空时由加密密钥派生）
+	AllowDevKey   bool   // 未配置加密密钥时是否允许使用开发环境密钥
+}
+
+// FieldCrypt 字段加密配置
+func (c CryptoConfig) FieldCrypt() fieldcrypt.Config {
+	return fieldcrypt.Config{
+		Key:           c.EncryptionKey,
+		BlindIndexKey: c.BlindIndexKey,
+		AllowDevKey:   c.AllowDevKey,
+	}
+}
+
 func LoadConfig() *Config {
 	return &Config{
 		App: AppConfig{
@@ -42,6 +85,30 @@ func LoadConfig() *Config {
 			Name:     getEnv("DB_NAME", "plms"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
+		Import: ImportConfig{
+			ProfileDir: getEnv("IMPORT_PROFILE_DIR", "./configs/import_profiles"),
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:   time.Duration(getEnvAsInt("ACCESS_TOKEN_TTL_MINUTES", 30)) * time.Minute,
+			RefreshTokenTTL:  time.Duration(getEnvAsInt("REFRESH_TOKEN_TTL_HOURS", 168)) * time.Hour,
+			MaxLoginFailures: getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+			LockDuration:     time.Duration(getEnvAsInt("LOGIN_LOCK_MINUTES", 15)) * time.Minute,
+			MaxIPFailures:    getEnvAsInt("LOGIN_MAX_IP_FAILURES", 20),
+			IPFailureWindow:  time.Duration(getEnvAsInt("LOGIN_IP_WINDOW_MINUTES", 15)) * time.Minute,
+			RequireAdmin2FA:  getEnvAsBool("AUTH_REQUIRE_ADMIN_2FA", false),
+		},
+		Password: PasswordConfig{
+			MinLength:      getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			MinCharClasses: getEnvAsInt("PASSWORD_MIN_CHAR_CLASSES", 3),
+			HistorySize:    getEnvAsInt("PASSWORD_HISTORY_SIZE", 5),
+			MaxAgeDays:     getEnvAsInt("PASSWORD_MAX_AGE_DAYS", 90),
+		},
+		Crypto: CryptoConfig{
+			EncryptionKey: getEnv("DATA_ENCRYPTION_KEY", ""),
+			BlindIndexKey: getEnv("DATA_BLIND_INDEX_KEY", ""),
+			// 未设置 APP_ENV 时运行环境默认为 development，开发环境密钥需显式设置 APP_ENV=development 才允许使用
+			AllowDevKey: os.Getenv("APP_ENV") == "development",
+		},
 	}
 }
 
@@ -65,3 +132,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
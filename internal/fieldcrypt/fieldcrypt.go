@@ -0,0 +1,162 @@
+// Package fieldcrypt 敏感字段加密存储：AES-GCM 加密字段值，HMAC-SHA256 盲索引用于精确查询
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// prefix 密文前缀，用于区分尚未迁移的明文数据
+const prefix = "enc:v1:"
+
+// devKeySeed 开发环境（APP_ENV=development）未配置密钥时使用的密钥种子，其他环境必须配置 DATA_ENCRYPTION_KEY
+const devKeySeed = "plms-dev-encryption-key-change-in-production"
+
+// errNotConfigured 未调用 Configure 设置密钥
+var errNotConfigured = errors.New("未配置加密密钥")
+
+// Config 加密配置
+type Config struct {
+	Key           string // 加密密钥（base64 编码的 32 字节）
+	BlindIndexKey string // 盲索引密钥（base64 编码，为空时由加密密钥派生）
+	AllowDevKey   bool   // 未配置加密密钥时是否允许使用开发环境密钥
+}
+
+var state = struct {
+	sync.RWMutex
+	aead     cipher.AEAD
+	indexKey []byte
+}{}
+
+// Configure 设置加密密钥及盲索引密钥
+// 未配置加密密钥时返回错误；只有开发环境使用由固定种子派生的密钥，数据不可视为加密
+func Configure(cfg Config) error {
+	if cfg.Key == "" {
+		if !cfg.AllowDevKey {
+			return errors.New("未配置 DATA_ENCRYPTION_KEY（仅 APP_ENV=development 时允许使用开发环境密钥）")
+		}
+		key := sha256.Sum256([]byte(devKeySeed))
+		return setKeys(key[:], nil)
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil {
+		return fmt.Errorf("加密密钥不是有效的 base64: %v", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("加密密钥长度应为 32 字节，实际为 %d 字节", len(key))
+	}
+	var indexKey []byte
+	if cfg.BlindIndexKey != "" {
+		if indexKey, err = base64.StdEncoding.DecodeString(cfg.BlindIndexKey); err != nil {
+			return fmt.Errorf("盲索引密钥不是有效的 base64: %v", err)
+		}
+		if len(indexKey) < 16 {
+			return errors.New("盲索引密钥长度不能少于 16 字节")
+		}
+	}
+	return setKeys(key, indexKey)
+}
+
+// setKeys 初始化 AES-GCM 及盲索引密钥，未指定盲索引密钥时由加密密钥派生
+func setKeys(key, indexKey []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	if len(indexKey) == 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("blind-index"))
+		indexKey = mac.Sum(nil)
+	}
+
+	state.Lock()
+	state.aead = aead
+	state.indexKey = indexKey
+	state.Unlock()
+	return nil
+}
+
+// IsEncrypted 值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 加密字段值，空值及已加密的值原样返回
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+	state.RLock()
+	aead := state.aead
+	state.RUnlock()
+	if aead == nil {
+		return "", errNotConfigured
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密字段值，未加密的值（迁移前的明文数据）原样返回
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %v", err)
+	}
+	state.RLock()
+	aead := state.aead
+	state.RUnlock()
+	if aead == nil {
+		return "", errNotConfigured
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("密文格式错误")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("解密失败，请检查加密密钥配置")
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex 计算字段值的盲索引（去除首尾空白并转大写后的 HMAC-SHA256），空值返回空字符串
+// 未配置密钥时 panic，避免用空密钥生成无法匹配的索引
+func BlindIndex(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+	state.RLock()
+	indexKey := state.indexKey
+	state.RUnlock()
+	if indexKey == nil {
+		panic(errNotConfigured)
+	}
+
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
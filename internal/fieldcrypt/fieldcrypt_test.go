@@ -0,0 +1,198 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// 测试用密钥（base64 编码的 32 字节）
+var (
+	testKey  = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	otherKey = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+// configure 设置测试密钥
+func configure(t *testing.T, key string) {
+	t.Helper()
+	if err := Configure(Config{Key: key}); err != nil {
+		t.Fatalf("Configure 返回错误: %v", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "有效密钥", cfg: Config{Key: testKey}},
+		{name: "指定盲索引密钥", cfg: Config{Key: testKey, BlindIndexKey: otherKey}},
+		{name: "开发环境未配置密钥", cfg: Config{AllowDevKey: true}},
+		{name: "非开发环境未配置密钥", cfg: Config{}, wantErr: true},
+		{name: "密钥不是 base64", cfg: Config{Key: "not base64!"}, wantErr: true},
+		{name: "密钥长度错误", cfg: Config{Key: base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+		{name: "盲索引密钥过短", cfg: Config{Key: testKey, BlindIndexKey: base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Configure(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	configure(t, testKey)
+	tests := []string{"11010519491231002X", "13812345678", "中文 联系电话", " 带空格 "}
+	for _, plaintext := range tests {
+		encrypted, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) 返回错误: %v", plaintext, err)
+		}
+		if !IsEncrypted(encrypted) || strings.Contains(encrypted, plaintext) {
+			t.Errorf("Encrypt(%q) = %q，应为不包含明文的密文", plaintext, encrypted)
+		}
+		decrypted, err := Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, decrypted, err)
+		}
+		// 每次加密使用随机 nonce，同一明文的密文不同
+		again, _ := Encrypt(plaintext)
+		if again == encrypted {
+			t.Errorf("Encrypt(%q) 两次加密的密文相同", plaintext)
+		}
+	}
+}
+
+func TestPassThrough(t *testing.T) {
+	configure(t, testKey)
+	encrypted, err := Encrypt("13812345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "空值", value: ""},
+		{name: "已加密的值", value: encrypted},
+	}
+	for _, tt := range tests {
+		if got, err := Encrypt(tt.value); err != nil || got != tt.value {
+			t.Errorf("%s: Encrypt(%q) = %q, %v，应原样返回", tt.name, tt.value, got, err)
+		}
+	}
+	// 迁移前的明文数据解密时原样返回
+	for _, value := range []string{"", "13812345678", "enc:v2:其他前缀"} {
+		if got, err := Decrypt(value); err != nil || got != value {
+			t.Errorf("Decrypt(%q) = %q, %v，应原样返回", value, got, err)
+		}
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	configure(t, testKey)
+	encrypted, err := Encrypt("11010519491231002X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configure(t, otherKey)
+	if _, err := Decrypt(encrypted); err == nil {
+		t.Error("使用其他密钥解密应返回错误")
+	}
+	if _, err := Decrypt(prefix + "not base64!"); err == nil {
+		t.Error("密文格式错误应返回错误")
+	}
+	if _, err := Decrypt(prefix + base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("密文长度不足应返回错误")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	configure(t, testKey)
+	index := BlindIndex("11010519491231002X")
+	if len(index) != 64 {
+		t.Fatalf("BlindIndex 长度 = %d, want 64", len(index))
+	}
+	tests := []struct {
+		name  string
+		value string
+		same  bool
+	}{
+		{name: "相同的值", value: "11010519491231002X", same: true},
+		{name: "小写及首尾空格", value: " 11010519491231002x ", same: true},
+		{name: "不同的值", value: "110105194912310021", same: false},
+	}
+	for _, tt := range tests {
+		if got := BlindIndex(tt.value); (got == index) != tt.same {
+			t.Errorf("%s: BlindIndex(%q) = %q，与原索引相同应为 %v", tt.name, tt.value, got, tt.same)
+		}
+	}
+	if got := BlindIndex("  "); got != "" {
+		t.Errorf("空值的盲索引 = %q, want \"\"", got)
+	}
+
+	// 盲索引由密钥决定：更换盲索引密钥后不同，重新配置相同密钥后一致
+	configure(t, otherKey)
+	if BlindIndex("11010519491231002X") == index {
+		t.Error("不同密钥的盲索引应不同")
+	}
+	configure(t, testKey)
+	if BlindIndex("11010519491231002X") != index {
+		t.Error("相同密钥的盲索引应一致")
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	configure(t, testKey)
+	type record struct {
+		Value string `gorm:"serializer:encrypted"`
+	}
+	field := &schema.Field{Name: "Value"}
+	ctx := context.Background()
+
+	dbValue, err := Serializer{}.Value(ctx, field, reflect.Value{}, "13812345678")
+	if err != nil {
+		t.Fatalf("Value 返回错误: %v", err)
+	}
+	encrypted, ok := dbValue.(string)
+	if !ok || !IsEncrypted(encrypted) {
+		t.Fatalf("Value 应返回密文，实际为 %v", dbValue)
+	}
+	if _, err := (Serializer{}).Value(ctx, field, reflect.Value{}, 1); err == nil {
+		t.Error("非字符串字段应返回错误")
+	}
+
+	tests := []struct {
+		name    string
+		dbValue interface{}
+		want    string
+	}{
+		{name: "密文", dbValue: encrypted, want: "13812345678"},
+		{name: "字节切片", dbValue: []byte(encrypted), want: "13812345678"},
+		{name: "明文", dbValue: "13812345678", want: "13812345678"},
+		{name: "NULL", dbValue: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst record
+			field := &schema.Field{
+				Name:           "Value",
+				ReflectValueOf: func(ctx context.Context, v reflect.Value) reflect.Value { return v.Field(0) },
+			}
+			if err := (Serializer{}).Scan(ctx, field, reflect.ValueOf(&dst).Elem(), tt.dbValue); err != nil {
+				t.Fatalf("Scan 返回错误: %v", err)
+			}
+			if dst.Value != tt.want {
+				t.Errorf("Scan 结果 = %q, want %q", dst.Value, tt.want)
+			}
+		})
+	}
+}
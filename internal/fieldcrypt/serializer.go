@@ -0,0 +1,49 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName 模型字段使用的序列化器名称：`gorm:"serializer:encrypted"`
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer GORM 序列化器：写入时加密，读取时解密（兼容尚未迁移的明文数据）
+type Serializer struct{}
+
+// Scan 读取数据库值并解密
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("加密字段 %s 不支持的数据库类型: %T", field.Name, dbValue)
+	}
+
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("字段 %s %v", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+// Value 加密字段值后写入数据库
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("加密字段 %s 只支持字符串类型", field.Name)
+	}
+	return Encrypt(value)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentAccess 获取 AccessMiddleware 加载的当前用户权限
+// 未加载时返回没有任何权限和楼栋的空权限，避免越权
+func currentAccess(c *gin.Context) *services.UserAccess {
+	if value, exists := c.Get("access"); exists {
+		if access, ok := value.(*services.UserAccess); ok && access != nil {
+			return access
+		}
+	}
+	return &services.UserAccess{}
+}
+
+// AccessHandler 角色权限处理器
+type AccessHandler struct {
+	db      *gorm.DB
+	service *services.AccessService
+}
+
+// NewAccessHandler 创建角色权限处理器实例
+func NewAccessHandler(db *gorm.DB) *AccessHandler {
+	return &AccessHandler{
+		db:      db,
+		service: services.NewAccessService(db),
+	}
+}
+
+// GetCurrentAccess 获取当前用户的权限及楼栋范围
+// GET /api/v1/auth/access
+func (h *AccessHandler) GetCurrentAccess(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    currentAccess(c),
+	})
+}
+
+// GetRoles 获取角色列表（管理员接口）
+// GET /api/v1/auth/roles
+func (h *AccessHandler) GetRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取角色列表失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    roles,
+	})
+}
+
+// SaveRole 新增或更新角色（管理员接口）
+// PUT /api/v1/auth/roles/:code
+func (h *AccessHandler) SaveRole(c *gin.Context) {
+	var req services.SaveRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.SaveRole(c.Param("code"), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色保存成功",
+		"data":    nil,
+	})
+}
+
+// GetUserBuildings 获取用户负责的楼栋（管理员接口）
+// GET /api/v1/auth/users/:id/buildings
+func (h *AccessHandler) GetUserBuildings(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	buildings, err := h.service.GetUserBuildings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取楼栋范围失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    buildings,
+	})
+}
+
+// SetUserBuildingsRequest 设置用户楼栋范围请求
+type SetUserBuildingsRequest struct {
+	Buildings []string `json:"buildings"`
+}
+
+// SetUserBuildings 设置用户负责的楼栋（管理员接口）
+// PUT /api/v1/auth/users/:id/buildings
+func (h *AccessHandler) SetUserBuildings(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req SetUserBuildingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.SetUserBuildings(userID, req.Buildings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "楼栋范围设置成功",
+		"data":    nil,
+	})
+}
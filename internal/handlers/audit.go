@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"PLMS/internal/models"
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditPersons 记录本次请求涉及的人员，由审计中间件写入审计日志
+func auditPersons(c *gin.Context, ids ...int64) {
+	var existing []int64
+	if value, exists := c.Get("auditPersonIDs"); exists {
+		existing = value.([]int64)
+	}
+	c.Set("auditPersonIDs", append(existing, ids...))
+}
+
+// auditPersonList 记录查询结果中的人员
+func auditPersonList(c *gin.Context, persons []models.Person) {
+	ids := make([]int64, 0, len(persons))
+	for _, person := range persons {
+		ids = append(ids, person.ID)
+	}
+	auditPersons(c, ids...)
+}
+
+// auditChange 记录写操作修改前后的数据（新增时 before 为 nil，删除时 after 为 nil）
+func auditChange(c *gin.Context, before, after interface{}) {
+	if before != nil {
+		c.Set("auditBefore", before)
+	}
+	if after != nil {
+		c.Set("auditAfter", after)
+	}
+}
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+// NewAuditHandler 创建审计日志处理器实例
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{service: services.NewAuditService(db)}
+}
+
+// GetAuditLogs 查询审计日志（仅管理员）
+// GET /api/v1/audit/logs?userId=&username=&action=&resource=&personId=&endpoint=&startTime=&endTime=&page=1&pageSize=20
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	var query services.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+
+	logs, total, err := h.service.GetAuditLogs(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取审计日志失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    logs,
+		"total":   total,
+		"current": query.Page,
+	})
+}
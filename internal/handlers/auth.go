@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"PLMS/internal/models"
 	"PLMS/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -24,6 +26,22 @@ func NewAuthHandler(db *gorm.DB) *AuthHandler {
 	}
 }
 
+// passwordErrorData 密码不符合策略时返回违反的规则，其他错误返回 nil
+func passwordErrorData(err error) interface{} {
+	if policyErr, ok := services.IsPasswordPolicyError(err); ok {
+		return gin.H{"violations": policyErr.Violations}
+	}
+	return nil
+}
+
+// clientInfo 获取请求的客户端信息（IP、User-Agent）
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
 // Login 用户登录
 // POST /api/v1/auth/login
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -37,7 +55,50 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.Login(&req)
+	result, err := h.service.Login(&req, clientInfo(c))
+	if err != nil {
+		// 账号锁定或IP限流
+		if errors.Is(err, services.ErrLoginThrottled) || errors.Is(err, services.ErrAccountLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	message := "登录成功"
+	if result.TwoFactorRequired {
+		message = "请输入双因素认证验证码"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// RefreshToken 使用刷新token换取新的访问token（刷新token同时轮换）
+// POST /api/v1/auth/refresh
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req services.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result, err := h.service.RefreshToken(&req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
@@ -49,7 +110,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "登录成功",
+		"message": "刷新成功",
 		"data":    result,
 	})
 }
@@ -111,23 +172,32 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": err.Error(),
-			"data":    nil,
+			"data":    passwordErrorData(err),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "密码修改成功",
+		"message": "密码修改成功，请重新登录",
 		"data":    nil,
 	})
 }
 
-// Logout 用户登出
+// Logout 用户登出（吊销当前 token）
 // POST /api/v1/auth/logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// JWT是无状态的，登出主要在客户端清除token
-	// 如果需要实现token黑名单，可以在这里将token加入黑名单
+	if value, exists := c.Get("claims"); exists {
+		if err := h.service.RevokeToken(value.(*services.CustomClaims), models.RevokeReasonLogout); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "登出失败",
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "登出成功",
@@ -135,7 +205,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
-// CheckDefaultPassword 检查是否使用默认密码
+// CheckDefaultPassword 检查是否使用默认密码及密码过期状态
 // GET /api/v1/auth/check-default-password
 func (h *AuthHandler) CheckDefaultPassword(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -148,7 +218,7 @@ func (h *AuthHandler) CheckDefaultPassword(c *gin.Context) {
 		return
 	}
 
-	isDefault, err := h.service.IsDefaultPassword(userID.(int64))
+	status, err := h.service.GetPasswordStatus(userID.(int64))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -162,7 +232,11 @@ func (h *AuthHandler) CheckDefaultPassword(c *gin.Context) {
 		"code":    200,
 		"message": "success",
 		"data": gin.H{
-			"isDefaultPassword": isDefault,
+			"isDefaultPassword":     status.IsDefaultPassword,
+			"passwordExpired":       status.PasswordExpired,
+			"passwordExpiresAt":     status.ExpiresAt,
+			"daysUntilExpiry":       status.DaysUntilExpiry,
+			"requireChangePassword": status.RequireChangePassword(),
 		},
 	})
 }
@@ -170,17 +244,6 @@ func (h *AuthHandler) CheckDefaultPassword(c *gin.Context) {
 // CreateUser 创建用户（管理员接口）
 // POST /api/v1/auth/users
 func (h *AuthHandler) CreateUser(c *gin.Context) {
-	// 检查当前用户是否为管理员
-	role, exists := c.Get("role")
-	if !exists || role.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"code":    403,
-			"message": "无权限，仅管理员可创建用户",
-			"data":    nil,
-		})
-		return
-	}
-
 	var req services.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -196,7 +259,7 @@ func (h *AuthHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": err.Error(),
-			"data":    nil,
+			"data":    passwordErrorData(err),
 		})
 		return
 	}
@@ -211,17 +274,6 @@ func (h *AuthHandler) CreateUser(c *gin.Context) {
 // DeleteUser 删除用户（管理员接口）
 // DELETE /api/v1/auth/users/:id
 func (h *AuthHandler) DeleteUser(c *gin.Context) {
-	// 检查当前用户是否为管理员
-	role, exists := c.Get("role")
-	if !exists || role.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"code":    403,
-			"message": "无权限，仅管理员可删除用户",
-			"data":    nil,
-		})
-		return
-	}
-
 	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -251,17 +303,6 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 // UpdateUser 更新用户信息（管理员接口）
 // PUT /api/v1/auth/users/:id
 func (h *AuthHandler) UpdateUser(c *gin.Context) {
-	// 检查当前用户是否为管理员
-	role, exists := c.Get("role")
-	if !exists || role.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"code":    403,
-			"message": "无权限，仅管理员可更新用户",
-			"data":    nil,
-		})
-		return
-	}
-
 	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -286,7 +327,7 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": err.Error(),
-			"data":    nil,
+			"data":    passwordErrorData(err),
 		})
 		return
 	}
@@ -301,17 +342,6 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 // GetUserList 获取用户列表（管理员接口，分页）
 // GET /api/v1/auth/users?page=1&pageSize=20
 func (h *AuthHandler) GetUserList(c *gin.Context) {
-	// 检查当前用户是否为管理员
-	role, exists := c.Get("role")
-	if !exists || role.(string) != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"code":    403,
-			"message": "无权限，仅管理员可查看用户列表",
-			"data":    nil,
-		})
-		return
-	}
-
 	// 获取分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if page < 1 {
@@ -349,3 +379,61 @@ func (h *AuthHandler) GetUserList(c *gin.Context) {
 		"current": page,
 	})
 }
+
+// ForceLogout 强制用户在所有设备下线（管理员接口）
+// POST /api/v1/auth/users/:id/logout
+func (h *AuthHandler) ForceLogout(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.ForceLogout(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已强制下线",
+		"data":    nil,
+	})
+}
+
+// UnlockUser 解除账号锁定（管理员接口）
+// POST /api/v1/auth/users/:id/unlock
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.UnlockUser(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "账号已解锁",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"PLMS/internal/models"
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ElectricBicycleHandler struct {
+	db      *gorm.DB
+	service *services.ElectricBicycleService
+}
+
+func NewElectricBicycleHandler(db *gorm.DB) *ElectricBicycleHandler {
+	return &ElectricBicycleHandler{
+		db:      db,
+		service: services.NewElectricBicycleService(db),
+	}
+}
+
+// scopedService 按当前用户权限范围操作的电动车服务
+func (h *ElectricBicycleHandler) scopedService(c *gin.Context) *services.ElectricBicycleService {
+	return h.service.WithAccess(currentAccess(c))
+}
+
+// GetBicycles 获取人员名下的电动车
+// GET /api/v1/persons/:id/bicycles
+func (h *ElectricBicycleHandler) GetBicycles(c *gin.Context) {
+	personId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的人员ID",
+		})
+		return
+	}
+	bicycles, err := h.scopedService(c).GetBicyclesByPerson(personId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "查询失败",
+		})
+		return
+	}
+	auditPersons(c, personId)
+	c.JSON(http.StatusOK, gin.H{
+		"data": bicycles,
+	})
+}
+
+// CreateBicycle 为人员新增电动车
+// POST /api/v1/persons/:id/bicycles
+func (h *ElectricBicycleHandler) CreateBicycle(c *gin.Context) {
+	personId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的人员ID",
+		})
+		return
+	}
+	var bicycle models.ElectricBicycle
+	if err := c.ShouldBindJSON(&bicycle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	saved, err := h.scopedService(c).CreateBicycle(personId, &bicycle)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, personId)
+	auditChange(c, nil, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// UpdateBicycle 更新电动车信息
+// PUT /api/v1/bicycles/:id
+func (h *ElectricBicycleHandler) UpdateBicycle(c *gin.Context) {
+	bicycleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电动车ID",
+		})
+		return
+	}
+	var bicycle models.ElectricBicycle
+	if err := c.ShouldBindJSON(&bicycle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetBicycle(bicycleId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	saved, err := service.UpdateBicycle(bicycleId, &bicycle)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, saved.PersonID)
+	auditChange(c, before, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// DeleteBicycle 删除电动车（软删除）
+// DELETE /api/v1/bicycles/:id
+func (h *ElectricBicycleHandler) DeleteBicycle(c *gin.Context) {
+	bicycleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电动车ID",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetBicycle(bicycleId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := service.DeleteBicycle(bicycleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, before.PersonID)
+	auditChange(c, before, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// FindByPlateNumber 根据车牌号查询车主
+// GET /api/v1/bicycles/lookup?plateNumber=xxx
+func (h *ElectricBicycleHandler) FindByPlateNumber(c *gin.Context) {
+	owners, err := h.scopedService(c).FindOwnersByPlateNumber(c.Query("plateNumber"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	for _, owner := range owners {
+		auditPersons(c, owner.Person.ID)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": owners,
+	})
+}
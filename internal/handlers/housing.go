@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"PLMS/internal/models"
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HousingHandler 楼栋、单元、房间处理器
+type HousingHandler struct {
+	service *services.HousingService
+}
+
+// NewHousingHandler 创建楼栋、单元、房间处理器实例
+func NewHousingHandler(db *gorm.DB) *HousingHandler {
+	return &HousingHandler{service: services.NewHousingService(db)}
+}
+
+// scopedService 按当前用户权限范围操作的服务
+func (h *HousingHandler) scopedService(c *gin.Context) *services.HousingService {
+	return h.service.WithAccess(currentAccess(c))
+}
+
+// GetBuildings 获取楼栋列表
+// GET /api/v1/buildings
+func (h *HousingHandler) GetBuildings(c *gin.Context) {
+	buildings, err := h.scopedService(c).GetBuildings()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "查询失败",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": buildings,
+	})
+}
+
+// CreateBuilding 新增楼栋
+// POST /api/v1/buildings
+func (h *HousingHandler) CreateBuilding(c *gin.Context) {
+	var building models.Building
+	if err := c.ShouldBindJSON(&building); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	saved, err := h.scopedService(c).CreateBuilding(&building)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, nil, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// UpdateBuilding 更新楼栋信息
+// PUT /api/v1/buildings/:id
+func (h *HousingHandler) UpdateBuilding(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的楼栋ID",
+		})
+		return
+	}
+	var building models.Building
+	if err := c.ShouldBindJSON(&building); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetBuilding(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	saved, err := service.UpdateBuilding(id, &building)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, before, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// DeleteBuilding 删除楼栋（软删除，楼栋下没有单元时才可删除）
+// DELETE /api/v1/buildings/:id
+func (h *HousingHandler) DeleteBuilding(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的楼栋ID",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetBuilding(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := service.DeleteBuilding(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, before, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// GetUnits 获取楼栋下的单元
+// GET /api/v1/buildings/:id/units
+func (h *HousingHandler) GetUnits(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的楼栋ID",
+		})
+		return
+	}
+	units, err := h.scopedService(c).GetUnits(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": units,
+	})
+}
+
+// CreateUnit 在楼栋下新增单元
+// POST /api/v1/buildings/:id/units
+func (h *HousingHandler) CreateUnit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的楼栋ID",
+		})
+		return
+	}
+	var unit models.Unit
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	saved, err := h.scopedService(c).CreateUnit(id, &unit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, nil, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// DeleteUnit 删除单元（软删除，单元下没有房间时才可删除）
+// DELETE /api/v1/units/:id
+func (h *HousingHandler) DeleteUnit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的单元ID",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetUnit(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := service.DeleteUnit(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, before, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// GetRooms 获取单元下的房间（包括没有住户的房间）
+// GET /api/v1/units/:id/rooms
+func (h *HousingHandler) GetRooms(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的单元ID",
+		})
+		return
+	}
+	rooms, err := h.scopedService(c).GetRooms(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": rooms,
+	})
+}
+
+// CreateRoom 在单元下新增房间
+// POST /api/v1/units/:id/rooms
+func (h *HousingHandler) CreateRoom(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的单元ID",
+		})
+		return
+	}
+	var room models.Room
+	if err := c.ShouldBindJSON(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	saved, err := h.scopedService(c).CreateRoom(id, &room)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, nil, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// UpdateRoom 更新房间信息（修改房号时同步更新住户的房号）
+// PUT /api/v1/rooms/:id
+func (h *HousingHandler) UpdateRoom(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房间ID",
+		})
+		return
+	}
+	var room models.Room
+	if err := c.ShouldBindJSON(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetRoom(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	saved, err := service.UpdateRoom(id, &room)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, before, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// DeleteRoom 删除房间（软删除，房间没有住户时才可删除）
+// DELETE /api/v1/rooms/:id
+func (h *HousingHandler) DeleteRoom(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的房间ID",
+		})
+		return
+	}
+	service := h.scopedService(c)
+	before, err := service.GetRoom(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := service.DeleteRoom(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditChange(c, before, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
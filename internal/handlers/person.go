@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -26,6 +27,11 @@ func NewPersonHandler(db *gorm.DB) *PersonHandler {
 	}
 }
 
+// scopedService 按当前用户权限范围查询的人员服务
+func (p *PersonHandler) scopedService(c *gin.Context) *services.PersonService {
+	return p.service.WithAccess(currentAccess(c))
+}
+
 func (p *PersonHandler) GetPersons(c *gin.Context) {
 	var filter models.PersonFilter
 
@@ -35,13 +41,14 @@ func (p *PersonHandler) GetPersons(c *gin.Context) {
 		})
 		return
 	}
-	persons, total, err := p.service.GetPersons(filter)
+	persons, total, err := p.scopedService(c).GetPersons(filter)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
 		})
 		return
 	}
+	auditPersonList(c, persons)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    persons,
 		"total":   total,
@@ -58,7 +65,7 @@ func (p *PersonHandler) GetRooms(c *gin.Context) {
 		})
 		return
 	}
-	roomList, total, err := p.service.GetRooms(filter)
+	roomList, total, err := p.scopedService(c).GetRooms(filter)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
@@ -80,13 +87,14 @@ func (p *PersonHandler) GetPersonInfo(c *gin.Context) {
 		})
 		return
 	}
-	personInfo, err := p.service.GetPersonInfo(personId)
+	personInfo, err := p.scopedService(c).GetPersonInfo(personId)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
 		})
 		return
 	}
+	auditPersons(c, personInfo.Person.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"data": personInfo,
 	})
@@ -95,20 +103,159 @@ func (p *PersonHandler) GetPersonInfoByRoom(c *gin.Context) {
 	buildingNumber := c.Query("buildingNumber")
 	unitNumber := c.Query("unitNumber")
 	roomNumber := c.Query("roomNumber")
-	personInfos, err := p.service.GetPersonInfoByRoom(buildingNumber, unitNumber, roomNumber)
+	personInfos, err := p.scopedService(c).GetPersonInfoByRoom(buildingNumber, unitNumber, roomNumber)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
 		})
 		return
 	}
+	for _, info := range personInfos {
+		auditPersons(c, info.Person.ID)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"data": personInfos,
 	})
 }
 
+// CreatePerson 新增人员
+// POST /api/v1/persons
+func (p *PersonHandler) CreatePerson(c *gin.Context) {
+	var person models.Person
+	if err := c.ShouldBindJSON(&person); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	saved, err := p.scopedService(c).CreatePerson(&person)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, saved.ID)
+	auditChange(c, nil, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// UpdatePerson 更新人员信息（只更新请求中包含的字段）
+// PUT /api/v1/persons/:id
+func (p *PersonHandler) UpdatePerson(c *gin.Context) {
+	personId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的人员ID",
+		})
+		return
+	}
+	// 只更新请求中包含的字段
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	var person models.Person
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(body, &person); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+	fields := make([]string, 0, len(sent))
+	for field := range sent {
+		fields = append(fields, field)
+	}
+	service := p.scopedService(c)
+	before, err := service.GetPerson(personId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	saved, err := service.UpdatePerson(personId, &person, fields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, personId)
+	auditChange(c, before, saved)
+	c.JSON(http.StatusOK, gin.H{
+		"data": saved,
+	})
+}
+
+// DeletePerson 删除人员（软删除）
+// DELETE /api/v1/persons/:id
+func (p *PersonHandler) DeletePerson(c *gin.Context) {
+	personId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的人员ID",
+		})
+		return
+	}
+	service := p.scopedService(c)
+	before, err := service.GetPerson(personId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := service.DeletePerson(personId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, personId)
+	auditChange(c, before, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// RevealSensitive 查看单个人员的身份证号、电话明文（需查看敏感信息权限，记录审计日志）
+// GET /api/v1/persons/:id/sensitive
+func (p *PersonHandler) RevealSensitive(c *gin.Context) {
+	personId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的人员ID",
+		})
+		return
+	}
+	info, err := p.scopedService(c).RevealSensitive(personId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, personId)
+	c.JSON(http.StatusOK, gin.H{
+		"data": info,
+	})
+}
+
 func (p *PersonHandler) GetBuildingNumbers(c *gin.Context) {
-	buildingNumbers, err := p.service.GetBuildingNumbers()
+	buildingNumbers, err := p.scopedService(c).GetBuildingNumbers()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
@@ -122,7 +269,7 @@ func (p *PersonHandler) GetBuildingNumbers(c *gin.Context) {
 
 func (p *PersonHandler) GetUnitNumbersByBuildingNumber(c *gin.Context) {
 	buildingNumber := c.Query("buildingNumber")
-	unitNumbers, err := p.service.GetUnitNumbersByBuildingNumber(buildingNumber)
+	unitNumbers, err := p.scopedService(c).GetUnitNumbersByBuildingNumber(buildingNumber)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
@@ -137,7 +284,7 @@ func (p *PersonHandler) GetUnitNumbersByBuildingNumber(c *gin.Context) {
 
 func (p *PersonHandler) GetPersonStatistics(c *gin.Context) {
 	buildingNumber := c.Query("buildingNumber")
-	stat, err := p.service.GetPersonStatistics(buildingNumber)
+	stat, err := p.scopedService(c).GetPersonStatistics(buildingNumber)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "查询失败",
@@ -162,7 +309,7 @@ func (p *PersonHandler) ExportPersons(c *gin.Context) {
 	}
 
 	// 查询数据（不分页）
-	persons, err := p.service.ExportPersons(filter)
+	persons, err := p.scopedService(c).ExportPersons(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "查询失败",
@@ -176,6 +323,7 @@ func (p *PersonHandler) ExportPersons(c *gin.Context) {
 		})
 		return
 	}
+	auditPersonList(c, persons)
 
 	// 如果没有指定导出字段，使用默认字段
 	if len(filter.ShowFields) == 0 {
@@ -235,6 +383,7 @@ func (p *PersonHandler) GetExportFields(c *gin.Context) {
 		{"field": "name", "header": models.GetExportFieldHeader("name")},
 		{"field": "id_card", "header": models.GetExportFieldHeader("id_card")},
 		{"field": "age", "header": models.GetExportFieldHeader("age")},
+		{"field": "birth_date", "header": models.GetExportFieldHeader("birth_date")},
 		{"field": "gender", "header": models.GetExportFieldHeader("gender")},
 		// 居住信息
 		{"field": "is_permanent", "header": models.GetExportFieldHeader("is_permanent")},
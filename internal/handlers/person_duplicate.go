@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+
+	"PLMS/internal/models"
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDuplicates 查询疑似重复的人员（仅管理员）
+// GET /api/v1/persons/duplicates?reason=&buildingNumber=&page=1&pageSize=20
+func (p *PersonHandler) GetDuplicates(c *gin.Context) {
+	var query services.DuplicateQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的查询参数",
+		})
+		return
+	}
+	switch query.Reason {
+	case "", services.DuplicateByIDCard, services.DuplicateByNamePhone, services.DuplicateByNameRoom:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "重复原因取值错误：id_card、name_phone、name_room",
+		})
+		return
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+
+	groups, total, err := p.scopedService(c).FindDuplicates(&query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	for _, group := range groups {
+		auditPersonList(c, group.Persons)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":    groups,
+		"total":   total,
+		"current": query.Page,
+	})
+}
+
+// MergePersons 合并重复人员（仅管理员）：被合并人员软删除，电动车转移到保留的人员
+// POST /api/v1/persons/merge
+func (p *PersonHandler) MergePersons(c *gin.Context) {
+	var req services.MergePersonsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的请求数据",
+		})
+		return
+	}
+
+	service := p.scopedService(c)
+	before := make([]*models.Person, 0, len(req.SourceIDs)+1)
+	for _, id := range append([]int64{req.TargetID}, req.SourceIDs...) {
+		person, err := service.GetPerson(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		before = append(before, person)
+	}
+
+	result, err := service.MergePersons(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	auditPersons(c, append([]int64{req.TargetID}, result.MergedIDs...)...)
+	auditChange(c, before, result)
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// GetPersonMerges 查询合并记录（仅管理员）
+// GET /api/v1/persons/merges?personId=&page=1&pageSize=20
+func (p *PersonHandler) GetPersonMerges(c *gin.Context) {
+	var query services.PersonMergeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的查询参数",
+		})
+		return
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+
+	merges, total, err := p.scopedService(c).GetPersonMerges(&query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "查询失败",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":    merges,
+		"total":   total,
+		"current": query.Page,
+	})
+}
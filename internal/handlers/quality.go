@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QualityHandler 数据质量处理器
+type QualityHandler struct {
+	service *services.QualityService
+}
+
+// NewQualityHandler 创建数据质量处理器实例
+func NewQualityHandler(db *gorm.DB) *QualityHandler {
+	return &QualityHandler{service: services.NewQualityService(db)}
+}
+
+// GetReport 按楼栋统计数据质量问题
+// GET /api/v1/quality?buildingNumber=&staleDays=90
+func (h *QualityHandler) GetReport(c *gin.Context) {
+	var query services.QualityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的查询参数",
+		})
+		return
+	}
+
+	report, err := h.service.WithAccess(currentAccess(c)).GetReport(&query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
+
+// GetIssueDetails 分页查看存在指定问题的人员；住房情况不一致时返回房间及住户
+// GET /api/v1/quality/:issue?buildingNumber=&staleDays=90&page=1&pageSize=20
+func (h *QualityHandler) GetIssueDetails(c *gin.Context) {
+	issue := c.Param("issue")
+	if !services.IsQualityIssue(issue) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "不支持的数据质量问题",
+		})
+		return
+	}
+	var query services.QualityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的查询参数",
+		})
+		return
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.PageSize > 100 {
+		query.PageSize = 100
+	}
+
+	service := h.service.WithAccess(currentAccess(c))
+	if issue == services.QualityHousingInconsistent {
+		rooms, total, err := service.GetInconsistentRooms(&query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, room := range rooms {
+			auditPersonList(c, room.Persons)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data":    rooms,
+			"total":   total,
+			"current": query.Page,
+		})
+		return
+	}
+
+	persons, total, err := service.GetIssuePersons(issue, &query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ids := make([]int64, 0, len(persons))
+	for _, person := range persons {
+		ids = append(ids, person.ID)
+	}
+	auditPersons(c, ids...)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    persons,
+		"total":   total,
+		"current": query.Page,
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessions 获取当前用户的登录会话（设备）列表
+// GET /api/v1/auth/sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var currentSessionID string
+	if value, exists := c.Get("claims"); exists {
+		currentSessionID = value.(*services.CustomClaims).SessionID
+	}
+
+	sessions, err := h.service.GetUserSessions(userID.(int64), currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取会话列表失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession 吊销当前用户的某个登录会话（该设备下线）
+// DELETE /api/v1/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	h.revokeSession(c, userID.(int64))
+}
+
+// GetSessionList 获取全部用户的登录会话（管理员接口）
+// GET /api/v1/auth/user-sessions?userId=&username=&page=&pageSize=
+func (h *AuthHandler) GetSessionList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	userID, _ := strconv.ParseInt(c.Query("userId"), 10, 64)
+
+	sessions, total, err := h.service.GetSessionList(&services.SessionQuery{
+		UserID:   userID,
+		Username: c.Query("username"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取会话列表失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    sessions,
+		"total":   total,
+		"current": page,
+	})
+}
+
+// AdminRevokeSession 吊销任意用户的登录会话（管理员接口）
+// DELETE /api/v1/auth/user-sessions/:id
+func (h *AuthHandler) AdminRevokeSession(c *gin.Context) {
+	h.revokeSession(c, 0)
+}
+
+// revokeSession 吊销会话，userID 为 0 时不校验会话所属用户
+func (h *AuthHandler) revokeSession(c *gin.Context, userID int64) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的会话ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.RevokeSession(userID, id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "会话已下线",
+		"data":    nil,
+	})
+}
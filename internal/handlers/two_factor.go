@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorCodeRequest 提交验证码请求
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyTwoFactorLogin 登录第二步：提交验证码或恢复码
+// POST /api/v1/auth/login/2fa
+func (h *AuthHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var req services.VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result, err := h.service.VerifyTwoFactorLogin(&req, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, services.ErrLoginThrottled) || errors.Is(err, services.ErrAccountLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data":    result,
+	})
+}
+
+// SetupTwoFactor 获取双因素认证绑定密钥
+// POST /api/v1/auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	result, err := h.service.SetupTwoFactor(userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    result,
+	})
+}
+
+// EnableTwoFactor 提交验证码启用双因素认证，返回恢复码（只展示一次）
+// POST /api/v1/auth/2fa/enable
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	codes, err := h.service.EnableTwoFactor(userID.(int64), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "双因素认证已启用，请妥善保存恢复码",
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor 停用双因素认证
+// POST /api/v1/auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req services.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(userID.(int64), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "双因素认证已停用",
+		"data":    nil,
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码作废
+// POST /api/v1/auth/2fa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID.(int64), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复码已重新生成，请妥善保存",
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// ResetTwoFactor 重置用户的双因素认证（仅管理员）
+// POST /api/v1/auth/users/:id/2fa/reset
+func (h *AuthHandler) ResetTwoFactor(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.service.ResetTwoFactor(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "双因素认证已重置",
+		"data":    nil,
+	})
+}
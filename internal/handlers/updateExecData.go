@@ -4,7 +4,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
+	"PLMS/internal/config"
+	"PLMS/internal/models"
 	"PLMS/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,18 +19,23 @@ type UpdateExecDataHandler struct {
 	service *services.UpdateExcelDataService
 }
 
-func NewUpdateExecDataHandler(db *gorm.DB) *UpdateExecDataHandler {
+func NewUpdateExecDataHandler(db *gorm.DB, cfg config.ImportConfig) *UpdateExecDataHandler {
 	return &UpdateExecDataHandler{
 		db:      db,
-		service: services.NewUpdateExcelDataService(db),
+		service: services.NewUpdateExcelDataService(db, cfg.ProfileDir),
 	}
 }
 
+// scopedService 按当前用户楼栋范围导入、回滚的服务
+func (h *UpdateExecDataHandler) scopedService(c *gin.Context) *services.UpdateExcelDataService {
+	return h.service.WithAccess(currentAccess(c))
+}
+
 func (h *UpdateExecDataHandler) UpdateExecData(filePath string) (*services.ImportResult, error) {
-	return h.service.ImportExcelData(filePath)
+	return h.service.ImportExcelData(filePath, services.ImportOptions{})
 }
 
-// ImportExcel 导入Excel文件（HTTP接口）
+// ImportExcel 导入Excel文件（HTTP接口，只导入当前用户有权访问的楼栋）
 // POST /api/v1/import/excel
 func (h *UpdateExecDataHandler) ImportExcel(c *gin.Context) {
 	// 获取上传的文件
@@ -60,8 +69,8 @@ func (h *UpdateExecDataHandler) ImportExcel(c *gin.Context) {
 		return
 	}
 
-	// 保存文件到临时目录
-	tempFilePath := filepath.Join(tempDir, header.Filename)
+	// 保存文件到临时目录（加时间戳前缀，避免同名文件相互覆盖）
+	tempFilePath := filepath.Join(tempDir, strconv.FormatInt(time.Now().UnixNano(), 10)+"_"+filepath.Base(header.Filename))
 	if err := c.SaveUploadedFile(header, tempFilePath); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -70,11 +79,39 @@ func (h *UpdateExecDataHandler) ImportExcel(c *gin.Context) {
 		return
 	}
 
-	// 处理完成后删除临时文件
-	defer os.Remove(tempFilePath)
+	// 导入选项：profile 指定导入方案；removeMissing=true 时软删除导入楼栋中表格里已不存在的人员
+	// transactional=true 时整个导入在一个事务中执行，任一工作表失败则全部回滚
+	opts := services.ImportOptions{
+		Profile:       c.PostForm("profile"),
+		RemoveMissing: c.PostForm("removeMissing") == "true",
+		Transactional: c.PostForm("transactional") == "true",
+		FileName:      header.Filename,
+		Operator:      c.GetString("username"),
+		OperatorID:    c.GetInt64("userID"),
+	}
+
+	// dryRun=true 时只预览，不写入数据库
+	if c.PostForm("dryRun") == "true" {
+		defer os.Remove(tempFilePath)
+		preview, err := h.scopedService(c).PreviewImport(tempFilePath, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "预览失败: " + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "Excel预览成功",
+			"data":    preview,
+		})
+		return
+	}
 
-	// 调用导入服务
-	result, err := h.service.ImportExcelData(tempFilePath)
+	// 默认后台执行并立即返回任务，async=false 时等待导入完成；临时文件由任务处理完成后删除
+	async := c.DefaultPostForm("async", "true") != "false"
+	job, err := h.scopedService(c).SubmitImportJob(tempFilePath, opts, async)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -82,16 +119,182 @@ func (h *UpdateExecDataHandler) ImportExcel(c *gin.Context) {
 		})
 		return
 	}
+	// 逐条人员变更记录在导入批次中，审计日志只记录导入任务
+	auditChange(c, nil, job.ImportJob)
+
+	if async {
+		c.JSON(http.StatusAccepted, gin.H{
+			"code":    202,
+			"message": "导入任务已提交",
+			"data":    job,
+		})
+		return
+	}
+
+	if job.Status == models.ImportJobFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "导入失败: " + job.Error,
+			"data":    job,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "Excel导入成功",
-		"data": gin.H{
-			"filename":     header.Filename,
-			"size":         header.Size,
-			"totalSheets":  result.TotalSheets,
-			"totalPersons": result.TotalPersons,
-			"details":      result.Details,
-		},
+		"data":    job,
+	})
+}
+
+// jobUploader 查询导入任务时的提交人用户ID：管理员可查看全部任务（返回 0），其他用户只能查看自己提交的任务
+func jobUploader(c *gin.Context) int64 {
+	if c.GetString("role") == models.RoleAdmin {
+		return 0
+	}
+	return c.GetInt64("userID")
+}
+
+// GetImportJob 获取导入任务状态及进度（非管理员只能查看自己提交的任务）
+// GET /api/v1/import/jobs/:id
+func (h *UpdateExecDataHandler) GetImportJob(c *gin.Context) {
+	jobID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的任务ID",
+		})
+		return
+	}
+
+	job, err := h.service.GetImportJob(jobID, jobUploader(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    job,
+	})
+}
+
+// GetImportJobList 获取导入历史（非管理员只能查看自己提交的任务）
+// GET /api/v1/import/jobs?page=1&pageSize=20
+func (h *UpdateExecDataHandler) GetImportJobList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	jobs, total, err := h.service.GetImportJobList(page, pageSize, jobUploader(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取导入历史失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    jobs,
+		"total":   total,
+		"current": page,
+	})
+}
+
+// CommitImportRequest 确认导入请求
+type CommitImportRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CommitImport 确认导入已预览的数据
+// POST /api/v1/import/commit
+func (h *UpdateExecDataHandler) CommitImport(c *gin.Context) {
+	var req CommitImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.scopedService(c).CommitImport(req.Token, c.GetInt64("userID"), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "导入失败: " + err.Error(),
+		})
+		return
+	}
+	auditChange(c, nil, result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "Excel导入成功",
+		"data":    result,
+	})
+}
+
+// RollbackImportBatch 回滚导入批次
+// POST /api/v1/import/batches/:id/rollback
+func (h *UpdateExecDataHandler) RollbackImportBatch(c *gin.Context) {
+	batchID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的批次ID",
+		})
+		return
+	}
+
+	batch, err := h.scopedService(c).RollbackImportBatch(batchID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "回滚失败: " + err.Error(),
+		})
+		return
+	}
+	auditChange(c, nil, batch)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "回滚成功",
+		"data":    batch,
+	})
+}
+
+// GetImportProfiles 获取可用的导入方案
+// GET /api/v1/import/profiles
+func (h *UpdateExecDataHandler) GetImportProfiles(c *gin.Context) {
+	profiles, err := h.service.ListImportProfiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取导入方案失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    profiles,
 	})
 }
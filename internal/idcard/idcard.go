@@ -0,0 +1,131 @@
+// Package idcard 居民身份证号码校验（GB 11643）：校验码、15位转18位，解析出生日期、性别及行政区划
+package idcard
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// weights 18位身份证号前17位的加权因子
+var weights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// checkCodes 校验码对照表（加权和对 11 取模为下标）
+const checkCodes = "10X98765432"
+
+// 性别
+const (
+	GenderMale   = 1 // 男
+	GenderFemale = 2 // 女
+)
+
+// provinces 省级行政区划代码（GB/T 2260 前两位）
+var provinces = map[string]string{
+	"11": "北京市", "12": "天津市", "13": "河北省", "14": "山西省", "15": "内蒙古自治区",
+	"21": "辽宁省", "22": "吉林省", "23": "黑龙江省",
+	"31": "上海市", "32": "江苏省", "33": "浙江省", "34": "安徽省", "35": "福建省", "36": "江西省", "37": "山东省",
+	"41": "河南省", "42": "湖北省", "43": "湖南省", "44": "广东省", "45": "广西壮族自治区", "46": "海南省",
+	"50": "重庆市", "51": "四川省", "52": "贵州省", "53": "云南省", "54": "西藏自治区",
+	"61": "陕西省", "62": "甘肃省", "63": "青海省", "64": "宁夏回族自治区", "65": "新疆维吾尔自治区",
+	"71": "台湾省", "81": "香港特别行政区", "82": "澳门特别行政区",
+}
+
+// Info 身份证号解析结果
+type Info struct {
+	Number     string    // 18位身份证号（15位号码已转换，校验码大写）
+	RegionCode string    // 6位行政区划代码
+	Province   string    // 省级行政区名称
+	BirthDate  time.Time // 出生日期
+	Gender     int       // 性别：1男，2女
+}
+
+// Age 到指定日期的周岁
+func (i *Info) Age(now time.Time) int {
+	return AgeAt(i.BirthDate, now)
+}
+
+// Parse 校验并解析身份证号，支持15位号码（自动转换为18位）
+func Parse(number string) (*Info, error) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	switch len(number) {
+	case 15:
+		converted, err := Convert15To18(number)
+		if err != nil {
+			return nil, err
+		}
+		number = converted
+	case 18:
+		if !isDigits(number[:17]) {
+			return nil, errors.New("身份证号包含非法字符")
+		}
+		if checkCode(number[:17]) != number[17] {
+			return nil, errors.New("身份证号校验位错误")
+		}
+	default:
+		return nil, errors.New("身份证号应为18位或15位")
+	}
+
+	province, ok := provinces[number[:2]]
+	if !ok {
+		return nil, errors.New("身份证号行政区划代码错误")
+	}
+	birth, err := time.ParseInLocation("20060102", number[6:14], time.Local)
+	if err != nil || birth.Year() < 1900 || birth.After(time.Now()) {
+		return nil, errors.New("身份证号出生日期错误")
+	}
+	gender := GenderFemale
+	if (number[16]-'0')%2 == 1 {
+		gender = GenderMale
+	}
+	return &Info{
+		Number:     number,
+		RegionCode: number[:6],
+		Province:   province,
+		BirthDate:  birth,
+		Gender:     gender,
+	}, nil
+}
+
+// Valid 身份证号是否有效
+func Valid(number string) bool {
+	_, err := Parse(number)
+	return err == nil
+}
+
+// Convert15To18 将15位身份证号转换为18位：出生年份补全为 19xx 并计算校验码
+func Convert15To18(number string) (string, error) {
+	number = strings.TrimSpace(number)
+	if len(number) != 15 || !isDigits(number) {
+		return "", errors.New("15位身份证号应全部为数字")
+	}
+	body := number[:6] + "19" + number[6:]
+	return body + string(checkCode(body)), nil
+}
+
+// AgeAt 计算到指定日期的周岁
+func AgeAt(birth, now time.Time) int {
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
+
+// checkCode 计算前17位对应的校验码
+func checkCode(body string) byte {
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(body[i]-'0') * weights[i]
+	}
+	return checkCodes[sum%11]
+}
+
+// isDigits 是否全部为数字
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package idcard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		number   string
+		wantErr  bool
+		want     string // 规范化后的18位号码
+		birth    string
+		gender   int
+		province string
+	}{
+		{name: "18位校验码为X", number: "11010519491231002X", want: "11010519491231002X", birth: "1949-12-31", gender: GenderFemale, province: "北京市"},
+		{name: "小写校验码", number: "11010519491231002x", want: "11010519491231002X", birth: "1949-12-31", gender: GenderFemale, province: "北京市"},
+		{name: "首尾空格", number: " 440304199003071213 ", want: "440304199003071213", birth: "1990-03-07", gender: GenderMale, province: "广东省"},
+		{name: "闰年2月29日", number: "110101200002290018", want: "110101200002290018", birth: "2000-02-29", gender: GenderMale, province: "北京市"},
+		{name: "15位转18位", number: "110105491231002", want: "11010519491231002X", birth: "1949-12-31", gender: GenderFemale, province: "北京市"},
+		{name: "校验码错误", number: "110105194912310021", wantErr: true},
+		{name: "15位包含字母", number: "11010549123100X", wantErr: true},
+		{name: "前17位包含字母", number: "1101051949123100AX", wantErr: true},
+		{name: "长度错误", number: "1101051949123100", wantErr: true},
+		{name: "空号码", number: "", wantErr: true},
+		{name: "行政区划错误", number: "990105194912310023", wantErr: true},
+		{name: "出生日期不存在", number: "11010120000230001X", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Parse(tt.number)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) 应返回错误，实际解析为 %+v", tt.number, info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) 返回错误: %v", tt.number, err)
+			}
+			if info.Number != tt.want {
+				t.Errorf("Number = %q, want %q", info.Number, tt.want)
+			}
+			if got := info.BirthDate.Format("2006-01-02"); got != tt.birth {
+				t.Errorf("BirthDate = %s, want %s", got, tt.birth)
+			}
+			if info.Gender != tt.gender {
+				t.Errorf("Gender = %d, want %d", info.Gender, tt.gender)
+			}
+			if info.Province != tt.province {
+				t.Errorf("Province = %q, want %q", info.Province, tt.province)
+			}
+			if info.RegionCode != tt.want[:6] {
+				t.Errorf("RegionCode = %q, want %q", info.RegionCode, tt.want[:6])
+			}
+		})
+	}
+}
+
+func TestConvert15To18(t *testing.T) {
+	tests := []struct {
+		number  string
+		want    string
+		wantErr bool
+	}{
+		{number: "110105491231002", want: "11010519491231002X"},
+		{number: "440304900307121", want: "440304199003071213"},
+		{number: "11010549123100", wantErr: true},
+		{number: "11010549123100A", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Convert15To18(tt.number)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Convert15To18(%q) 应返回错误，实际为 %q", tt.number, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Convert15To18(%q) = %q, %v, want %q", tt.number, got, err, tt.want)
+		}
+	}
+}
+
+func TestAgeAt(t *testing.T) {
+	birth := time.Date(1990, 3, 7, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		now  string
+		want int
+	}{
+		{now: "2020-03-06", want: 29},
+		{now: "2020-03-07", want: 30},
+		{now: "2020-12-31", want: 30},
+		{now: "1990-03-07", want: 0},
+	}
+	for _, tt := range tests {
+		now, _ := time.ParseInLocation("2006-01-02", tt.now, time.Local)
+		if got := AgeAt(birth, now); got != tt.want {
+			t.Errorf("AgeAt(1990-03-07, %s) = %d, want %d", tt.now, got, tt.want)
+		}
+	}
+}
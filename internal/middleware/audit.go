@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"PLMS/internal/models"
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditMaxFilterSize 审计日志中保存的查询条件最大长度
+const auditMaxFilterSize = 64 * 1024
+
+// Audit 审计中间件：请求处理完成后记录操作用户、接口、查询条件、涉及的人员及修改前后的数据
+// 涉及的人员及修改前后的数据由处理器写入上下文（auditPersonIDs、auditBefore、auditAfter）
+func Audit(db *gorm.DB, resource, action string) gin.HandlerFunc {
+	auditService := services.NewAuditService(db)
+	return func(c *gin.Context) {
+		filter := c.Request.URL.RawQuery
+		if c.ContentType() == "application/json" && c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				// 写操作的请求体即修改后的数据，只记录查询类接口的请求体
+				if isReadAction(action) && len(body) > 0 {
+					filter = strings.TrimPrefix(filter+"\n"+string(body), "\n")
+				}
+			}
+		}
+
+		c.Next()
+
+		entry := &models.AuditLog{
+			UserID:     c.GetInt64("userID"),
+			Username:   c.GetString("username"),
+			Role:       c.GetString("role"),
+			Action:     action,
+			Resource:   resource,
+			Method:     c.Request.Method,
+			Endpoint:   c.Request.URL.Path,
+			ClientIP:   c.ClientIP(),
+			Filter:     truncateString(filter, auditMaxFilterSize),
+			StatusCode: c.Writer.Status(),
+		}
+		if value, exists := c.Get("auditPersonIDs"); exists {
+			entry.PersonIDs = value.([]int64)
+		}
+		if value, exists := c.Get("auditBefore"); exists {
+			entry.BeforeData = marshalAuditData(value)
+		}
+		if value, exists := c.Get("auditAfter"); exists {
+			entry.AfterData = marshalAuditData(value)
+		}
+
+		if err := auditService.Record(entry); err != nil {
+			log.Printf("保存审计日志失败: %v, user: %s, endpoint: %s", err, entry.Username, entry.Endpoint)
+		}
+	}
+}
+
+// isReadAction 是否为查询类操作
+func isReadAction(action string) bool {
+	switch action {
+	case models.AuditActionList, models.AuditActionView, models.AuditActionExport, models.AuditActionReveal:
+		return true
+	}
+	return false
+}
+
+// marshalAuditData 将修改前后的数据序列化为 JSON
+func marshalAuditData(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// truncateString 按字节截断字符串（不截断多字节字符）
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
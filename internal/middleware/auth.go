@@ -11,8 +11,9 @@ import (
 	"gorm.io/gorm"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware JWT认证中间件（同时检查 token 是否已被吊销）
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+	authService := services.NewAuthService(db)
 	return func(c *gin.Context) {
 		// 从请求头获取Authorization
 		authHeader := c.GetHeader("Authorization")
@@ -59,19 +60,57 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 特殊用途token（如双因素认证待验证）不能访问接口
+		if claims.Purpose != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "token无效或已过期",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查 token 是否已被吊销（登出、修改密码、禁用、强制下线）
+		revoked, err := authService.IsTokenRevoked(claims)
+		if err != nil {
+			fmt.Printf("[Auth] 500: 检查token吊销状态失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "token校验失败",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			fmt.Printf("[Auth] 401: Token已吊销, userID: %d\n", claims.UserID)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "token已失效，请重新登录",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
 		fmt.Printf("[Auth] Token验证成功, userID: %d, username: %s\n", claims.UserID, claims.Username)
 
+		// 记录会话最后活跃时间
+		authService.TouchSession(claims.SessionID, c.ClientIP())
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
 }
 
-// DefaultPasswordCheckMiddleware 检查是否使用默认密码
-// 如果使用默认密码，只允许访问修改密码和登录相关接口
+// DefaultPasswordCheckMiddleware 检查是否使用默认密码或密码已过期
+// 如果使用默认密码或密码已过期，只允许访问修改密码和登录相关接口
 func DefaultPasswordCheckMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
@@ -90,20 +129,76 @@ func DefaultPasswordCheckMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 获取用户服务检查是否使用默认密码
+		// 获取用户服务检查是否使用默认密码、密码是否过期
 		authService := services.NewAuthService(db)
-		isDefault, err := authService.IsDefaultPassword(userID.(int64))
+		status, err := authService.GetPasswordStatus(userID.(int64))
 		if err != nil {
 			c.Next()
 			return
 		}
 
-		if isDefault {
+		if status.RequireChangePassword() {
+			message := "请先修改默认密码"
+			if !status.IsDefaultPassword {
+				message = "密码已过期，请先修改密码"
+			}
 			c.JSON(http.StatusForbidden, gin.H{
 				"code":    403,
-				"message": "请先修改默认密码",
+				"message": message,
 				"data": gin.H{
 					"requireChangePassword": true,
+					"isDefaultPassword":     status.IsDefaultPassword,
+					"passwordExpired":       status.PasswordExpired,
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TwoFactorSetupMiddleware 配置要求管理员启用双因素认证时，未启用的管理员只能访问绑定及登录相关接口
+func TwoFactorSetupMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists || !services.TwoFactorRequiredForRole(c.GetString("role")) {
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		if path == "/api/v1/auth/2fa/setup" ||
+			path == "/api/v1/auth/2fa/enable" ||
+			path == "/api/v1/auth/access" ||
+			path == "/api/v1/auth/logout" ||
+			path == "/api/v1/auth/current-user" ||
+			path == "/api/v1/auth/change-password" ||
+			path == "/api/v1/auth/check-default-password" {
+			c.Next()
+			return
+		}
+
+		authService := services.NewAuthService(db)
+		enabled, err := authService.IsTwoFactorEnabled(userID.(int64))
+		if err != nil {
+			fmt.Printf("[Auth] 500: 检查双因素认证状态失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "双因素认证状态校验失败",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		if !enabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "请先启用双因素认证",
+				"data": gin.H{
+					"requireTwoFactorSetup": true,
 				},
 			})
 			c.Abort()
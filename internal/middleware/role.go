@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"PLMS/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequireRole 角色校验中间件，需在 AuthMiddleware 之后使用
+// 当前用户角色不在 roles 中时返回 403
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" || !slices.Contains(roles, role) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "无权限访问该接口",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AccessMiddleware 加载当前用户的权限及楼栋范围，需在 AuthMiddleware 之后使用
+// 结果存入上下文 access（*services.UserAccess）
+func AccessMiddleware(db *gorm.DB) gin.HandlerFunc {
+	accessService := services.NewAccessService(db)
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		access, err := accessService.GetUserAccess(userID.(int64), c.GetString("role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取用户权限失败",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+		c.Set("access", access)
+
+		c.Next()
+	}
+}
+
+// RequirePermission 权限校验中间件，需在 AccessMiddleware 之后使用
+// 当前用户不具备 permission 时返回 403
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("access")
+		access, ok := value.(*services.UserAccess)
+		if !ok || access == nil || !access.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "无权限访问该接口",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// 审计操作类型
+const (
+	AuditActionList   = "list"   // 查询列表
+	AuditActionView   = "view"   // 查看详情
+	AuditActionExport = "export" // 导出
+	AuditActionCreate = "create" // 新增
+	AuditActionUpdate = "update" // 修改
+	AuditActionDelete = "delete" // 删除
+	AuditActionImport = "import" // 导入、回滚
+	AuditActionReveal = "reveal" // 查看敏感信息明文
+	AuditActionMerge  = "merge"  // 合并重复人员
+)
+
+// 审计对象
+const (
+	AuditResourcePerson  = "person"  // 住户
+	AuditResourceBicycle = "bicycle" // 电动车
+	AuditResourceImport  = "import"  // Excel导入
+	AuditResourceHousing = "housing" // 楼栋、单元、房间
+)
+
+// AuditLog 审计日志：记录谁在何时查看、导出、修改了哪些住户数据
+type AuditLog struct {
+	ID         int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     int64     `json:"userId" gorm:"column:user_id;comment:操作用户ID"`
+	Username   string    `json:"username" gorm:"column:username;size:50;comment:操作用户名"`
+	Role       string    `json:"role" gorm:"column:role;size:50;comment:操作用户角色"`
+	Action     string    `json:"action" gorm:"column:action;size:20;not null;comment:操作类型"`
+	Resource   string    `json:"resource" gorm:"column:resource;size:20;not null;comment:操作对象"`
+	Method     string    `json:"method" gorm:"column:method;size:10;comment:请求方法"`
+	Endpoint   string    `json:"endpoint" gorm:"column:endpoint;size:255;comment:请求路径"`
+	ClientIP   string    `json:"clientIp" gorm:"column:client_ip;size:64;comment:客户端IP"`
+	Filter     string    `json:"filter" gorm:"column:filter;type:mediumtext;serializer:encrypted;comment:查询条件（查询参数及请求体，加密存储）"`
+	BeforeData string    `json:"beforeData" gorm:"column:before_data;type:mediumtext;serializer:encrypted;comment:修改前数据（JSON，加密存储）"`
+	AfterData  string    `json:"afterData" gorm:"column:after_data;type:mediumtext;serializer:encrypted;comment:修改后数据（JSON，加密存储）"`
+	StatusCode int       `json:"statusCode" gorm:"column:status_code;comment:响应状态码"`
+	PersonNum  int       `json:"personNum" gorm:"column:person_num;default:0;comment:涉及人员数"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	// PersonIDs 涉及的人员ID（保存在 audit_log_person）
+	PersonIDs []int64 `json:"personIds" gorm:"-"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// AuditLogPerson 审计日志涉及的人员，用于按人员查询访问记录
+type AuditLogPerson struct {
+	ID       int64 `json:"id" gorm:"primaryKey;autoIncrement"`
+	AuditID  int64 `json:"auditId" gorm:"column:audit_id;not null;comment:审计日志ID"`
+	PersonID int64 `json:"personId" gorm:"column:person_id;not null;comment:人员ID"`
+}
+
+// TableName 指定表名
+func (AuditLogPerson) TableName() string {
+	return "audit_log_person"
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Building 楼栋
+type Building struct {
+	ID             int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                                        // 主键ID
+	BuildingNumber string    `gorm:"column:building_number;type:varchar(20);not null;uniqueIndex" json:"building_number"` // 楼号
+	Name           string    `gorm:"column:name;type:varchar(100)" json:"name"`                                           // 名称
+	FloorCount     int       `gorm:"column:floor_count;default:0" json:"floor_count"`                                     // 总层数，0 表示未知
+	Remark         string    `gorm:"column:remark;type:varchar(500)" json:"remark"`                                       // 备注
+	IsDel          int8      `gorm:"column:is_del;default:0" json:"is_del"`                                               // 软删除标记
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`                                  // 创建时间
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`                                  // 更新时间
+}
+
+// TableName 指定表名
+func (Building) TableName() string {
+	return "building"
+}
+
+// Unit 单元
+type Unit struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                                // 主键ID
+	BuildingID int64     `gorm:"column:building_id;not null;uniqueIndex:uk_building_unit" json:"building_id"` // 所属楼栋ID
+	UnitNumber int       `gorm:"column:unit_number;not null;uniqueIndex:uk_building_unit" json:"unit_number"` // 单元号
+	IsDel      int8      `gorm:"column:is_del;default:0" json:"is_del"`                                       // 软删除标记
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`                          // 创建时间
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`                          // 更新时间
+}
+
+// TableName 指定表名
+func (Unit) TableName() string {
+	return "unit"
+}
+
+// Room 房间：没有住户的房间同样保留（空置房）
+type Room struct {
+	ID           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                                              // 主键ID
+	BuildingID   int64     `gorm:"column:building_id;not null;index" json:"building_id"`                                      // 所属楼栋ID
+	UnitID       int64     `gorm:"column:unit_id;not null;uniqueIndex:uk_unit_room" json:"unit_id"`                           // 所属单元ID
+	RoomNumber   string    `gorm:"column:room_number;type:varchar(100);not null;uniqueIndex:uk_unit_room" json:"room_number"` // 房号
+	Floor        int       `gorm:"column:floor;default:0" json:"floor"`                                                       // 楼层，0 表示未知
+	Area         float64   `gorm:"column:area;type:decimal(10,2);default:0" json:"area"`                                      // 建筑面积（平方米）
+	PropertyType string    `gorm:"column:property_type;type:varchar(50)" json:"property_type"`                                // 房屋性质（商品房、公租房等）
+	Remark       string    `gorm:"column:remark;type:varchar(500)" json:"remark"`                                             // 备注
+	IsDel        int8      `gorm:"column:is_del;default:0" json:"is_del"`                                                     // 软删除标记
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`                                        // 创建时间
+	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`                                        // 更新时间
+}
+
+// TableName 指定表名
+func (Room) TableName() string {
+	return "room"
+}
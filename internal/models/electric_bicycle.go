@@ -21,3 +21,9 @@ type ElectricBicycle struct {
 func (ElectricBicycle) TableName() string {
 	return "electric_bicycle"
 }
+
+// ElectricBicycleOwner 电动车及车主信息（用于车牌查询）
+type ElectricBicycleOwner struct {
+	Bicycle ElectricBicycle `json:"bicycle"`
+	Person  Person          `json:"person"`
+}
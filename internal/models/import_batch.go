@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// 导入批次状态
+const (
+	ImportBatchRunning    = "running"     // 导入中
+	ImportBatchCompleted  = "completed"   // 导入完成
+	ImportBatchPartial    = "partial"     // 部分完成（有工作表处理失败，其余工作表已保存）
+	ImportBatchFailed     = "failed"      // 导入失败（整体事务已回滚）
+	ImportBatchRolledBack = "rolled_back" // 已回滚
+)
+
+// 导入批次变更类型
+const (
+	ImportChangeInsert = "insert" // 新增人员
+	ImportChangeUpdate = "update" // 更新人员
+	ImportChangeRemove = "remove" // 软删除人员
+)
+
+// ImportBatch Excel导入批次
+type ImportBatch struct {
+	ID            int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                     // 批次ID
+	FileName      string     `gorm:"column:file_name;type:varchar(255)" json:"file_name"`              // 文件名
+	Profile       string     `gorm:"column:profile;type:varchar(100)" json:"profile"`                  // 导入方案
+	Operator      string     `gorm:"column:operator;type:varchar(50)" json:"operator"`                 // 操作人
+	Transactional int8       `gorm:"column:transactional;type:tinyint;default:0" json:"transactional"` // 是否整体事务：1是，0否（按工作表事务）
+	Status        string     `gorm:"column:status;type:varchar(20)" json:"status"`                     // 状态
+	Inserted      int        `gorm:"column:inserted;default:0" json:"inserted"`                        // 新增人数
+	Updated       int        `gorm:"column:updated;default:0" json:"updated"`                          // 更新人数
+	Removed       int        `gorm:"column:removed;default:0" json:"removed"`                          // 软删除人数
+	RolledBackAt  *time.Time `gorm:"column:rolled_back_at" json:"rolled_back_at"`                      // 回滚时间
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`               // 创建时间
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`               // 更新时间
+}
+
+// TableName 指定表名
+func (ImportBatch) TableName() string {
+	return "import_batch"
+}
+
+// ImportBatchChange 导入批次中的人员变更，用于回滚
+type ImportBatchChange struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`       // 主键ID
+	BatchID   int64     `gorm:"column:batch_id;not null;index" json:"batch_id"`     // 批次ID
+	PersonID  int64     `gorm:"column:person_id;not null;index" json:"person_id"`   // 人员ID
+	Action    string    `gorm:"column:action;type:varchar(20)" json:"action"`       // 变更类型：insert/update/remove
+	OldValues string    `gorm:"column:old_values;type:text" json:"old_values"`      // 更新前的字段值（JSON）
+	NewValues string    `gorm:"column:new_values;type:text" json:"new_values"`      // 导入写入的字段值（JSON），回滚前用于检查是否已被修改
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"` // 创建时间
+}
+
+// TableName 指定表名
+func (ImportBatchChange) TableName() string {
+	return "import_batch_change"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// 导入任务状态
+const (
+	ImportJobPending   = "pending"   // 排队中
+	ImportJobRunning   = "running"   // 处理中
+	ImportJobCompleted = "completed" // 已完成
+	ImportJobFailed    = "failed"    // 失败
+)
+
+// ImportJob Excel导入任务
+type ImportJob struct {
+	ID            int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                       // 任务ID
+	FileName      string     `gorm:"column:file_name;type:varchar(255)" json:"file_name"`                // 文件名
+	FileHash      string     `gorm:"column:file_hash;type:varchar(64);index" json:"file_hash"`           // 文件SHA-256
+	FileSize      int64      `gorm:"column:file_size" json:"file_size"`                                  // 文件大小（字节）
+	Uploader      string     `gorm:"column:uploader;type:varchar(50)" json:"uploader"`                   // 上传人
+	UploaderID    int64      `gorm:"column:uploader_id;index" json:"uploader_id"`                        // 上传人用户ID
+	Profile       string     `gorm:"column:profile;type:varchar(100)" json:"profile"`                    // 导入方案
+	RemoveMissing int8       `gorm:"column:remove_missing;type:tinyint;default:0" json:"remove_missing"` // 是否删除表中已不存在的人员
+	Transactional int8       `gorm:"column:transactional;type:tinyint;default:0" json:"transactional"`   // 是否整体事务
+	Status        string     `gorm:"column:status;type:varchar(20)" json:"status"`                       // 状态
+	BatchID       int64      `gorm:"column:batch_id" json:"batch_id"`                                    // 导入批次ID
+	Error         string     `gorm:"column:error;type:text" json:"error"`                                // 失败原因
+	ProgressJSON  string     `gorm:"column:progress;type:text" json:"-"`                                 // 各工作表进度（JSON）
+	ResultJSON    string     `gorm:"column:result;type:mediumtext" json:"-"`                             // 导入结果（JSON）
+	StartedAt     *time.Time `gorm:"column:started_at" json:"started_at"`                                // 开始时间
+	FinishedAt    *time.Time `gorm:"column:finished_at" json:"finished_at"`                              // 结束时间
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`                 // 创建时间
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`                 // 更新时间
+}
+
+// TableName 指定表名
+func (ImportJob) TableName() string {
+	return "import_job"
+}
@@ -1,57 +1,70 @@
 package models
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/idcard"
+
+	"gorm.io/gorm"
 )
 
 // Person 人员信息台账
 type Person struct {
-	ID                      int64     `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
-	BuildingNumber          string    `gorm:"column:building_number;not null;type:varchar(20)" json:"building_number"`
-	UnitNumber              int       `gorm:"column:unit_number;type:int" json:"unit_number"`
-	RoomNumber              string    `gorm:"column:room_number;not null;type:varchar(100)" json:"room_number"`
-	Name                    string    `gorm:"column:name;type:varchar(50)" json:"name"`
-	IDCard                  string    `gorm:"column:id_card;type:varchar(20);index" json:"id_card"`
-	Age                     int       `gorm:"column:age;type:int;index" json:"age"`
-	Gender                  int       `gorm:"column:gender;type:tinyint" json:"gender"`
-	IsPermanent             int       `gorm:"column:is_permanent;type:tinyint" json:"is_permanent"`
-	HousingSituation        string    `gorm:"column:housing_situation;type:varchar(100)" json:"housing_situation"`
-	PropertyNature          string    `gorm:"column:property_nature;type:varchar(200)" json:"property_nature"`
-	RegisteredResidenceType int       `gorm:"column:registered_residence_type;type:tinyint" json:"registered_residence_type"`
-	RegisteredResidence     string    `gorm:"column:registered_residence;type:varchar(200)" json:"registered_residence"`
-	Telephone               string    `gorm:"column:telephone;type:varchar(50)" json:"telephone"`
-	FirstContact            string    `gorm:"column:first_contact;type:varchar(50)" json:"first_contact"`
-	ElderRelationship       string    `gorm:"column:elder_relationship;type:varchar(50)" json:"elder_relationship"`
-	ElderContactPhone       string    `gorm:"column:elder_contact_phone;type:varchar(20)" json:"elder_contact_phone"`
-	SpecialSituation        string    `gorm:"column:special_situation;type:text" json:"special_situation"`
-	HasElectricCar          int       `gorm:"column:has_electric_car;type:tinyint" json:"has_electric_car"`
-	DisabilityLevel         string    `gorm:"column:disability_level;type:varchar(100)" json:"disability_level"`
-	IsLowIncome             int       `gorm:"column:is_low_income;type:tinyint" json:"is_low_income"`
-	IsLowIncome2            int       `gorm:"column:is_low_income2;type:tinyint" json:"is_low_income2"`
-	IsDestitute             int       `gorm:"column:is_destitute;type:tinyint" json:"is_destitute"`
-	IsFamilyPlanningSpecial int       `gorm:"column:is_family_planning_special;type:tinyint" json:"is_family_planning_special"`
-	DisabilityCategory      string    `gorm:"column:disability_category;type:varchar(100)" json:"disability_category"`
-	IsLivingAlone           int       `gorm:"column:is_living_alone;type:tinyint" json:"is_living_alone"`
-	IsEmptyNest             int       `gorm:"column:is_empty_nest;type:tinyint" json:"is_empty_nest"`
-	IsOrphaned              int       `gorm:"column:is_orphaned;type:tinyint" json:"is_orphaned"`
-	IsNeedsFocus            int       `gorm:"column:is_needs_focus;type:tinyint" json:"is_needs_focus"`
-	OtherSituation          string    `gorm:"column:other_situation;type:text" json:"other_situation"`
-	LicensePlate            string    `gorm:"column:license_plate;type:varchar(20)" json:"license_plate"`
-	BrandModel              string    `gorm:"column:brand_model;type:varchar(100)" json:"brand_model"`
-	IsInGroup               int       `gorm:"column:is_in_group;type:tinyint" json:"is_in_group"`
-	IsPrivateMessage        int       `gorm:"column:is_private_message;type:tinyint" json:"is_private_message"`
-	HasPet                  int       `gorm:"column:has_pet;type:tinyint" json:"has_pet"`
-	LastContactTime         string    `gorm:"column:last_contact_time;type:varchar(500)" json:"last_contact_time"`
-	OtherInfo               string    `gorm:"column:other_info;type:text" json:"other_info"`
-	IsCp                    int       `gorm:"column:is_cp;type:tinyint;default:0" json:"is_cp"`       // 是否党员：0否，1是
-	CpJoiningDay            *string   `gorm:"column:cp_joining_day;type:date" json:"cp_joining_day"`  // 入党日
-	Nationality             string    `gorm:"column:nationality;type:varchar(50)" json:"nationality"` // 民族
-	Education               string    `gorm:"column:education;type:varchar(50)" json:"education"`     // 学历
-	CpRemark                string    `gorm:"column:cp_remark;type:text" json:"cp_remark"`            // 党员备注
-	IsDel                   int       `gorm:"column:is_del;type:tinyint;default:0" json:"is_del"`
-	CreatedAt               time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt               time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID                      int64      `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	BuildingNumber          string     `gorm:"column:building_number;not null;type:varchar(20)" json:"building_number"`
+	UnitNumber              int        `gorm:"column:unit_number;type:int" json:"unit_number"`
+	RoomNumber              string     `gorm:"column:room_number;not null;type:varchar(100)" json:"room_number"`
+	RoomID                  *int64     `gorm:"column:room_id;index" json:"room_id"` // 所属房间ID（与楼号、单元、房号一致）
+	Name                    string     `gorm:"column:name;type:varchar(50)" json:"name"`
+	IDCard                  string     `gorm:"column:id_card;type:varchar(255);serializer:encrypted" json:"id_card"` // 加密存储
+	Age                     int        `gorm:"column:age;type:int;index" json:"age"`
+	BirthDate               *time.Time `gorm:"column:birth_date;type:date;index" json:"birth_date"` // 出生日期（有效身份证号时按身份证号计算）
+	Gender                  int        `gorm:"column:gender;type:tinyint" json:"gender"`
+	IsPermanent             int        `gorm:"column:is_permanent;type:tinyint" json:"is_permanent"`
+	HousingSituation        string     `gorm:"column:housing_situation;type:varchar(100)" json:"housing_situation"`
+	PropertyNature          string     `gorm:"column:property_nature;type:varchar(200)" json:"property_nature"`
+	RegisteredResidenceType int        `gorm:"column:registered_residence_type;type:tinyint" json:"registered_residence_type"`
+	RegisteredResidence     string     `gorm:"column:registered_residence;type:varchar(200)" json:"registered_residence"`
+	Telephone               string     `gorm:"column:telephone;type:varchar(255);serializer:encrypted" json:"telephone"` // 加密存储
+	FirstContact            string     `gorm:"column:first_contact;type:varchar(50)" json:"first_contact"`
+	ElderRelationship       string     `gorm:"column:elder_relationship;type:varchar(50)" json:"elder_relationship"`
+	ElderContactPhone       string     `gorm:"column:elder_contact_phone;type:varchar(255);serializer:encrypted" json:"elder_contact_phone"` // 加密存储
+	SpecialSituation        string     `gorm:"column:special_situation;type:text" json:"special_situation"`
+	HasElectricCar          int        `gorm:"column:has_electric_car;type:tinyint" json:"has_electric_car"`
+	DisabilityLevel         string     `gorm:"column:disability_level;type:varchar(100)" json:"disability_level"`
+	IsLowIncome             int        `gorm:"column:is_low_income;type:tinyint" json:"is_low_income"`
+	IsLowIncome2            int        `gorm:"column:is_low_income2;type:tinyint" json:"is_low_income2"`
+	IsDestitute             int        `gorm:"column:is_destitute;type:tinyint" json:"is_destitute"`
+	IsFamilyPlanningSpecial int        `gorm:"column:is_family_planning_special;type:tinyint" json:"is_family_planning_special"`
+	DisabilityCategory      string     `gorm:"column:disability_category;type:varchar(100)" json:"disability_category"`
+	IsLivingAlone           int        `gorm:"column:is_living_alone;type:tinyint" json:"is_living_alone"`
+	IsEmptyNest             int        `gorm:"column:is_empty_nest;type:tinyint" json:"is_empty_nest"`
+	IsOrphaned              int        `gorm:"column:is_orphaned;type:tinyint" json:"is_orphaned"`
+	IsNeedsFocus            int        `gorm:"column:is_needs_focus;type:tinyint" json:"is_needs_focus"`
+	OtherSituation          string     `gorm:"column:other_situation;type:text" json:"other_situation"`
+	LicensePlate            string     `gorm:"column:license_plate;type:varchar(20)" json:"license_plate"`
+	BrandModel              string     `gorm:"column:brand_model;type:varchar(100)" json:"brand_model"`
+	IsInGroup               int        `gorm:"column:is_in_group;type:tinyint" json:"is_in_group"`
+	IsPrivateMessage        int        `gorm:"column:is_private_message;type:tinyint" json:"is_private_message"`
+	HasPet                  int        `gorm:"column:has_pet;type:tinyint" json:"has_pet"`
+	LastContactTime         string     `gorm:"column:last_contact_time;type:varchar(500)" json:"last_contact_time"`
+	OtherInfo               string     `gorm:"column:other_info;type:text" json:"other_info"`
+	IsCp                    int        `gorm:"column:is_cp;type:tinyint;default:0" json:"is_cp"`       // 是否党员：0否，1是
+	CpJoiningDay            *string    `gorm:"column:cp_joining_day;type:date" json:"cp_joining_day"`  // 入党日
+	Nationality             string     `gorm:"column:nationality;type:varchar(50)" json:"nationality"` // 民族
+	Education               string     `gorm:"column:education;type:varchar(50)" json:"education"`     // 学历
+	CpRemark                string     `gorm:"column:cp_remark;type:text" json:"cp_remark"`            // 党员备注
+	IDCardBidx              string     `gorm:"column:id_card_bidx;type:char(64);index" json:"-"`       // 身份证号盲索引
+	TelephoneBidx           string     `gorm:"column:telephone_bidx;type:char(64);index" json:"-"`     // 电话盲索引
+	IsDel                   int        `gorm:"column:is_del;type:tinyint;default:0" json:"is_del"`
+	CreatedAt               time.Time  `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt               time.Time  `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -59,6 +72,121 @@ func (Person) TableName() string {
 	return "person"
 }
 
+// personReadonlyFields 由系统维护、不能通过修改接口设置的字段（json 名称）
+var personReadonlyFields = map[string]bool{
+	"id": true, "is_del": true, "created_at": true, "updated_at": true,
+}
+
+// ApplyFields 将 src 中指定的字段（json 名称）复制到 p，未指定的字段保持不变
+// 未知字段及系统维护的字段忽略
+func (p *Person) ApplyFields(src *Person, fields []string) {
+	dst := reflect.ValueOf(p).Elem()
+	from := reflect.ValueOf(src).Elem()
+	typ := dst.Type()
+	for _, field := range fields {
+		if personReadonlyFields[field] {
+			continue
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			if name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ","); name == field {
+				dst.Field(i).Set(from.Field(i))
+				break
+			}
+		}
+	}
+}
+
+// BeforeSave 保存前更新身份证号、电话的盲索引
+func (p *Person) BeforeSave(tx *gorm.DB) error {
+	p.IDCardBidx = fieldcrypt.BlindIndex(p.IDCard)
+	p.TelephoneBidx = fieldcrypt.BlindIndex(p.Telephone)
+	return nil
+}
+
+// AfterFind 按出生日期（或身份证号）计算年龄，避免使用录入时的过期年龄
+func (p *Person) AfterFind(tx *gorm.DB) error {
+	if p.BirthDate != nil {
+		p.Age = idcard.AgeAt(*p.BirthDate, time.Now())
+	} else if info, err := idcard.Parse(p.IDCard); err == nil {
+		p.Age = info.Age(time.Now())
+	}
+	return nil
+}
+
+// ApplyIDCard 身份证号有效时规范化号码（15位转18位），并按身份证号设置出生日期、性别和年龄
+// 返回身份证号是否有效，无效时不做修改
+func (p *Person) ApplyIDCard(now time.Time) bool {
+	info, err := idcard.Parse(p.IDCard)
+	if err != nil {
+		return false
+	}
+	birthDate := info.BirthDate
+	p.IDCard = info.Number
+	p.BirthDate = &birthDate
+	p.Gender = info.Gender
+	p.Age = info.Age(now)
+	return true
+}
+
+// BirthDateValue 出生日期的列值（yyyy-mm-dd），未知时为 nil
+func (p *Person) BirthDateValue() interface{} {
+	if p.BirthDate == nil {
+		return nil
+	}
+	return p.BirthDate.Format("2006-01-02")
+}
+
+// CpJoiningDayValue 入党日期的列值，未知时为 nil
+func (p *Person) CpJoiningDayValue() interface{} {
+	if p.CpJoiningDay == nil {
+		return nil
+	}
+	return *p.CpJoiningDay
+}
+
+// personBlindIndexColumns 需要维护盲索引的加密列（列名 -> 盲索引列名）
+var personBlindIndexColumns = map[string]string{
+	"id_card":   "id_card_bidx",
+	"telephone": "telephone_bidx",
+}
+
+// EncryptPersonValues 加密按列名更新的字段值并补充盲索引列
+// 使用 map 更新时 GORM 不会调用序列化器及钩子，需要先调用此方法
+// 已加密的值保持原密文，只在缺少盲索引列时解密后补充
+func EncryptPersonValues(values map[string]interface{}) error {
+	for _, column := range []string{"id_card", "telephone", "elder_contact_phone"} {
+		value, ok := values[column]
+		if !ok {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("字段 %s 的值应为字符串", column)
+		}
+		bidxColumn, hasBidx := personBlindIndexColumns[column]
+		if fieldcrypt.IsEncrypted(str) {
+			if _, exists := values[bidxColumn]; !hasBidx || exists {
+				continue
+			}
+			plaintext, err := fieldcrypt.Decrypt(str)
+			if err != nil {
+				return err
+			}
+			values[bidxColumn] = fieldcrypt.BlindIndex(plaintext)
+			continue
+		}
+		encrypted, err := fieldcrypt.Encrypt(str)
+		if err != nil {
+			return err
+		}
+		values[column] = encrypted
+		if hasBidx {
+			values[bidxColumn] = fieldcrypt.BlindIndex(str)
+		}
+	}
+	return nil
+}
+
 // GetExportValue 获取字段导出值（带转换）
 func (p *Person) GetExportValue(field string) string {
 	switch field {
@@ -74,6 +202,11 @@ func (p *Person) GetExportValue(field string) string {
 		return p.IDCard
 	case "age":
 		return strconv.Itoa(p.Age)
+	case "birth_date":
+		if p.BirthDate != nil {
+			return p.BirthDate.Format("2006-01-02")
+		}
+		return ""
 	case "gender":
 		switch p.Gender {
 		case 1:
@@ -185,53 +318,132 @@ func convertResidenceType(val int) string {
 	}
 }
 
+// exportFieldHeaders 导出字段中文名（导入时同样按这些表头识别字段）
+var exportFieldHeaders = map[string]string{
+	"building_number":            "楼号",
+	"unit_number":                "单元",
+	"room_number":                "房间号",
+	"name":                       "姓名",
+	"id_card":                    "身份证号",
+	"age":                        "年龄",
+	"birth_date":                 "出生日期",
+	"gender":                     "性别",
+	"telephone":                  "电话",
+	"is_permanent":               "是否常驻",
+	"housing_situation":          "住房情况",
+	"property_nature":            "房屋性质",
+	"registered_residence":       "户籍地",
+	"registered_residence_type":  "户籍情况",
+	"is_living_alone":            "是否独居",
+	"is_empty_nest":              "是否空巢",
+	"has_electric_car":           "是否有电动车",
+	"license_plate":              "车牌号",
+	"is_low_income":              "是否低保",
+	"is_low_income2":             "是否低收入",
+	"is_destitute":               "是否特困",
+	"has_pet":                    "是否有宠物",
+	"is_cp":                      "是否党员",
+	"nationality":                "民族",
+	"education":                  "学历",
+	"cp_joining_day":             "入党日期",
+	"cp_remark":                  "党员备注",
+	"first_contact":              "第一联系人",
+	"elder_relationship":         "与老人关系",
+	"elder_contact_phone":        "紧急联系电话",
+	"special_situation":          "特殊情况",
+	"disability_level":           "失能等级",
+	"is_family_planning_special": "是否计生特殊家庭",
+	"disability_category":        "残疾类别及等级",
+	"is_orphaned":                "是否孤寡",
+	"is_needs_focus":             "是否重点关注",
+	"other_situation":            "其他情况",
+	"brand_model":                "电动车品牌型号",
+	"is_in_group":                "是否入群",
+	"is_private_message":         "是否私信",
+	"last_contact_time":          "最后联系时间",
+	"other_info":                 "其他信息",
+}
+
 // GetExportFieldHeader 获取导出字段中文名
 func GetExportFieldHeader(field string) string {
-	headers := map[string]string{
-		"building_number":            "楼号",
-		"unit_number":                "单元",
-		"room_number":                "房间号",
-		"name":                       "姓名",
-		"id_card":                    "身份证号",
-		"age":                        "年龄",
-		"gender":                     "性别",
-		"telephone":                  "电话",
-		"is_permanent":               "是否常驻",
-		"housing_situation":          "住房情况",
-		"property_nature":            "房屋性质",
-		"registered_residence":       "户籍地",
-		"registered_residence_type":  "户籍情况",
-		"is_living_alone":            "是否独居",
-		"is_empty_nest":              "是否空巢",
-		"has_electric_car":           "是否有电动车",
-		"license_plate":              "车牌号",
-		"is_low_income":              "是否低保",
-		"is_low_income2":             "是否低收入",
-		"is_destitute":               "是否特困",
-		"has_pet":                    "是否有宠物",
-		"is_cp":                      "是否党员",
-		"nationality":                "民族",
-		"education":                  "学历",
-		"cp_joining_day":             "入党日期",
-		"cp_remark":                  "党员备注",
-		"first_contact":              "第一联系人",
-		"elder_relationship":         "与老人关系",
-		"elder_contact_phone":        "紧急联系电话",
-		"special_situation":          "特殊情况",
-		"disability_level":           "失能等级",
-		"is_family_planning_special": "是否计生特殊家庭",
-		"disability_category":        "残疾类别及等级",
-		"is_orphaned":                "是否孤寡",
-		"is_needs_focus":             "是否重点关注",
-		"other_situation":            "其他情况",
-		"brand_model":                "电动车品牌型号",
-		"is_in_group":                "是否入群",
-		"is_private_message":         "是否私信",
-		"last_contact_time":          "最后联系时间",
-		"other_info":                 "其他信息",
-	}
-	if h, ok := headers[field]; ok {
+	if h, ok := exportFieldHeaders[field]; ok {
 		return h
 	}
 	return field
 }
+
+// importFieldAliases 导入时可识别的表头别名（除导出表头外）
+var importFieldAliases = map[string][]string{
+	"name":                       {"姓名"},
+	"id_card":                    {"身份证号码", "身份证", "证件号码"},
+	"age":                        {"年龄"},
+	"birth_date":                 {"出生年月", "出生日期", "生日"},
+	"gender":                     {"性别"},
+	"is_permanent":               {"是否常住", "常住"},
+	"housing_situation":          {"居住情况"},
+	"property_nature":            {"房产性质"},
+	"registered_residence_type":  {"户籍类型"},
+	"registered_residence":       {"户籍所在地"},
+	"telephone":                  {"联系方式", "本人电话", "手机号", "手机号码"},
+	"first_contact":              {"第一联系人"},
+	"elder_relationship":         {"关系", "关系(老人之)"},
+	"elder_contact_phone":        {"联系电话"},
+	"special_situation":          {"特殊情况"},
+	"disability_level":           {"失能等级"},
+	"is_low_income":              {"低保"},
+	"is_low_income2":             {"低收入"},
+	"is_destitute":               {"是否特困供养", "特困供养", "特困"},
+	"is_family_planning_special": {"是否计划生育特殊家庭", "计划生育特殊家庭", "计生特殊家庭"},
+	"disability_category":        {"残疾类别", "残疾类别及等级"},
+	"is_living_alone":            {"独居"},
+	"is_empty_nest":              {"空巢"},
+	"is_orphaned":                {"孤寡"},
+	"other_situation":            {"其它情况"},
+	"is_needs_focus":             {"是否需重点关注", "重点关注"},
+	"is_in_group":                {"入群"},
+	"is_private_message":         {"私信"},
+	"has_pet":                    {"家有宠物", "宠物"},
+	"last_contact_time":          {"末次联系时间"},
+	"other_info":                 {"其他", "其它"},
+}
+
+// headerCleaner 去除表头中的空白及括号内的说明文字
+var headerCleaner = regexp.MustCompile(`\s+|[(（][^)）]*[)）]`)
+
+// NormalizeHeader 规范化表头文字，用于表头匹配
+func NormalizeHeader(header string) string {
+	return headerCleaner.ReplaceAllString(strings.TrimSpace(header), "")
+}
+
+// importHeaderFields 规范化后的表头 -> 导入字段，由导出表头和别名生成
+var importHeaderFields = buildImportHeaderFields()
+
+// buildImportHeaderFields 生成表头查找表，同一表头对应多个字段时直接报错
+func buildImportHeaderFields() map[string]string {
+	lookup := make(map[string]string)
+	add := func(field, header string) {
+		normalized := NormalizeHeader(header)
+		if normalized == "" {
+			return
+		}
+		if existing, ok := lookup[normalized]; ok && existing != field {
+			panic(fmt.Sprintf("导入表头 [%s] 同时对应字段 %s 和 %s", header, existing, field))
+		}
+		lookup[normalized] = field
+	}
+	for field, header := range exportFieldHeaders {
+		add(field, header)
+	}
+	for field, aliases := range importFieldAliases {
+		for _, alias := range aliases {
+			add(field, alias)
+		}
+	}
+	return lookup
+}
+
+// MatchImportFieldHeader 根据表头文字匹配导入字段（匹配导出表头及别名）
+func MatchImportFieldHeader(header string) (string, bool) {
+	field, ok := importHeaderFields[NormalizeHeader(header)]
+	return field, ok
+}
@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestNormalizeHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "姓名", want: "姓名"},
+		{header: " 姓 名 ", want: "姓名"},
+		{header: "身份证号\n", want: "身份证号"},
+		{header: "电话（手机）", want: "电话"},
+		{header: "年龄(岁)", want: "年龄"},
+		{header: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHeader(tt.header); got != tt.want {
+			t.Errorf("NormalizeHeader(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatchImportFieldHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{header: "楼号", want: "building_number", ok: true},
+		{header: "身份证号", want: "id_card", ok: true},
+		{header: "身份证号码", want: "id_card", ok: true},
+		{header: "身份证号码（18位）", want: "id_card", ok: true},
+		{header: "联系方式", want: "telephone", ok: true},
+		{header: " 本人 电话 ", want: "telephone", ok: true},
+		{header: "序号"},
+		{header: ""},
+	}
+	for _, tt := range tests {
+		got, ok := MatchImportFieldHeader(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("MatchImportFieldHeader(%q) = %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SensitiveFields 敏感字段，无查看敏感信息权限时脱敏显示
+var SensitiveFields = []string{"id_card", "telephone", "elder_contact_phone"}
+
+// phoneDigits 电话字段中的号码（可能包含多个号码或分机号）
+var phoneDigits = regexp.MustCompile(`\d{5,}`)
+
+// maskMiddle 保留前 keepHead 位和后 keepTail 位，其余替换为 *
+func maskMiddle(s string, keepHead, keepTail int) string {
+	runes := []rune(s)
+	if len(runes) <= keepHead+keepTail {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:keepHead]) + strings.Repeat("*", len(runes)-keepHead-keepTail) + string(runes[len(runes)-keepTail:])
+}
+
+// MaskIDCard 身份证号脱敏，如 110***********1234
+func MaskIDCard(idCard string) string {
+	if idCard == "" {
+		return ""
+	}
+	if len([]rune(idCard)) < 10 {
+		return maskMiddle(idCard, 1, 1)
+	}
+	return maskMiddle(idCard, 3, 4)
+}
+
+// MaskPhone 电话号码脱敏，如 138****5678；字段中包含多个号码时分别脱敏
+func MaskPhone(phone string) string {
+	return phoneDigits.ReplaceAllStringFunc(phone, func(number string) string {
+		if len(number) >= 11 {
+			return maskMiddle(number, 3, 4)
+		}
+		return maskMiddle(number, 2, 2)
+	})
+}
+
+// IsMasked 是否为脱敏后的值
+func IsMasked(value string) bool {
+	return strings.Contains(value, "*")
+}
+
+// MaskSensitive 将敏感字段替换为脱敏值（JSON 响应及 GetExportValue 均输出脱敏值）
+func (p *Person) MaskSensitive() {
+	// 出生日期即身份证号中间8位，有身份证号时一并隐藏
+	if p.IDCard != "" {
+		p.BirthDate = nil
+	}
+	p.IDCard = MaskIDCard(p.IDCard)
+	p.Telephone = MaskPhone(p.Telephone)
+	p.ElderContactPhone = MaskPhone(p.ElderContactPhone)
+}
+
+// KeepMaskedFields 提交的敏感字段仍为脱敏值时保留原值（前端回传脱敏数据时避免覆盖真实号码）
+func (p *Person) KeepMaskedFields(existing *Person) {
+	if IsMasked(p.IDCard) {
+		p.IDCard = existing.IDCard
+		if p.BirthDate == nil {
+			p.BirthDate = existing.BirthDate
+		}
+	}
+	if IsMasked(p.Telephone) {
+		p.Telephone = existing.Telephone
+	}
+	if IsMasked(p.ElderContactPhone) {
+		p.ElderContactPhone = existing.ElderContactPhone
+	}
+}
+
+// PersonSensitiveInfo 人员敏感信息明文（查看明文接口返回）
+type PersonSensitiveInfo struct {
+	ID                int64  `json:"id"`
+	IDCard            string `json:"id_card"`
+	Telephone         string `json:"telephone"`
+	ElderContactPhone string `json:"elder_contact_phone"`
+}
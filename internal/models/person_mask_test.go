@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestMaskIDCard(t *testing.T) {
+	tests := []struct {
+		idCard string
+		want   string
+	}{
+		{idCard: "", want: ""},
+		{idCard: "11010519491231002X", want: "110***********002X"},
+		{idCard: "110105491231002", want: "110********1002"},
+		{idCard: "123456789", want: "1*******9"},
+		{idCard: "12", want: "**"},
+		{idCard: "护照E1234567890", want: "护照E******7890"},
+	}
+	for _, tt := range tests {
+		if got := MaskIDCard(tt.idCard); got != tt.want {
+			t.Errorf("MaskIDCard(%q) = %q, want %q", tt.idCard, got, tt.want)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{phone: "", want: ""},
+		{phone: "13812345678", want: "138****5678"},
+		{phone: "010-62345678", want: "010-62****78"},
+		{phone: "13812345678/13987654321", want: "138****5678/139****4321"},
+		{phone: "62345678转123", want: "62****78转123"},
+		{phone: "1234", want: "1234"},
+		{phone: "无", want: "无"},
+	}
+	for _, tt := range tests {
+		if got := MaskPhone(tt.phone); got != tt.want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
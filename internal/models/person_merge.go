@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PersonMerge 重复人员合并记录：被合并人员软删除，电动车转移到保留的人员
+type PersonMerge struct {
+	ID             int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                         // 主键ID
+	TargetID       int64     `gorm:"column:target_person_id;not null;index" json:"target_person_id"`       // 保留的人员ID
+	SourceID       int64     `gorm:"column:source_person_id;not null;index" json:"source_person_id"`       // 被合并（软删除）的人员ID
+	Reason         string    `gorm:"column:reason;type:varchar(20)" json:"reason"`                         // 重复原因
+	Operator       string    `gorm:"column:operator;type:varchar(50)" json:"operator"`                     // 操作人
+	FilledFields   string    `gorm:"column:filled_fields;type:varchar(1000)" json:"filled_fields"`         // 从被合并人员补充到保留人员的字段（逗号分隔）
+	BicycleNum     int64     `gorm:"column:bicycle_num;default:0" json:"bicycle_num"`                      // 转移的电动车数
+	SourceSnapshot string    `gorm:"column:source_snapshot;type:mediumtext;serializer:encrypted" json:"-"` // 被合并人员合并前的数据（JSON，加密存储）
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`                   // 合并时间
+}
+
+// TableName 指定表名
+func (PersonMerge) TableName() string {
+	return "person_merge"
+}
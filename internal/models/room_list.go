@@ -1,12 +1,20 @@
 package models
 
 type RoomList struct {
+	//房间ID
+	RoomID int64 `json:"roomId"`
 	//楼号
 	BuildingNumber string `json:"buildingNumber"`
 	//单元
 	UnitNumber int `json:"unitNumber"`
 	//房号
 	RoomNumber string `json:"roomNumber"`
+	//楼层
+	Floor int `json:"floor"`
+	//建筑面积
+	Area float64 `json:"area"`
+	//房屋性质
+	PropertyType string `json:"propertyType"`
 	//联系人
 	ContactName string `json:"contactName"`
 	//居住人数
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SysPasswordHistory 用户历史密码（用于禁止重复使用最近的密码）
+type SysPasswordHistory struct {
+	ID           int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       int64     `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	PasswordHash string    `json:"-" gorm:"column:password_hash;size:255;not null;comment:密码（BCrypt加密）"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (SysPasswordHistory) TableName() string {
+	return "sys_password_history"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SysRecoveryCode 双因素认证恢复码（每个只能使用一次）
+type SysRecoveryCode struct {
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int64      `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;size:64;not null;comment:恢复码的SHA-256"`
+	UsedAt    *time.Time `json:"usedAt" gorm:"column:used_at;comment:使用时间"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (SysRecoveryCode) TableName() string {
+	return "sys_recovery_code"
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SysRefreshToken 刷新token
+// 每次刷新都会签发新的刷新token（同一 FamilyID），旧的标记为已使用；
+// 已使用的刷新token再次出现视为泄露，整个 FamilyID 及用户的全部token失效
+type SysRefreshToken struct {
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int64      `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;not null;unique;comment:刷新token的SHA-256"`
+	FamilyID  string     `json:"familyId" gorm:"column:family_id;size:64;not null;comment:登录会话ID，同一次登录轮换出的刷新token相同"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"column:expires_at;not null;comment:过期时间"`
+	UsedAt    *time.Time `json:"usedAt" gorm:"column:used_at;comment:已轮换时间"`
+	RevokedAt *time.Time `json:"revokedAt" gorm:"column:revoked_at;comment:吊销时间"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (SysRefreshToken) TableName() string {
+	return "sys_refresh_token"
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// 权限标识
+const (
+	PermViewSensitive = "person:view_sensitive" // 查看敏感字段（身份证号、电话等）
+	PermExport        = "person:export"         // 导出人员信息
+	PermImport        = "person:import"         // Excel导入及回滚
+	PermEdit          = "person:edit"           // 新增、修改、删除人员及电动车
+)
+
+// AllPermissions 系统支持的全部权限
+var AllPermissions = []string{PermViewSensitive, PermExport, PermImport, PermEdit}
+
+// SysRole 角色表
+type SysRole struct {
+	ID           int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code         string    `json:"code" gorm:"size:50;not null;unique;comment:角色编码"`
+	Name         string    `json:"name" gorm:"size:50;not null;comment:角色名称"`
+	Description  string    `json:"description" gorm:"size:255;comment:描述"`
+	AllBuildings int8      `json:"allBuildings" gorm:"column:all_buildings;type:tinyint(1);default:0;comment:是否可访问全部楼栋：1是，0否（按用户楼栋范围）"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"column:updated_at;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (SysRole) TableName() string {
+	return "sys_role"
+}
+
+// SysRolePermission 角色权限表
+type SysRolePermission struct {
+	ID         int64  `json:"id" gorm:"primaryKey;autoIncrement"`
+	RoleCode   string `json:"roleCode" gorm:"column:role_code;size:50;not null;comment:角色编码"`
+	Permission string `json:"permission" gorm:"size:100;not null;comment:权限标识"`
+}
+
+// TableName 指定表名
+func (SysRolePermission) TableName() string {
+	return "sys_role_permission"
+}
+
+// SysUserBuilding 用户负责的楼栋
+type SysUserBuilding struct {
+	ID             int64  `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         int64  `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	BuildingNumber string `json:"buildingNumber" gorm:"column:building_number;size:50;not null;comment:楼号"`
+}
+
+// TableName 指定表名
+func (SysUserBuilding) TableName() string {
+	return "sys_user_building"
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// token 吊销原因
+const (
+	RevokeReasonLogout          = "logout"
+	RevokeReasonPasswordChanged = "password_changed"
+	RevokeReasonUserDisabled    = "user_disabled"
+	RevokeReasonUserUpdated     = "user_updated"
+	RevokeReasonForceLogout     = "force_logout"
+	RevokeReasonRefreshReused   = "refresh_token_reused"
+	RevokeReasonTwoFactorUsed   = "2fa_pending_used"
+	RevokeReasonSessionRevoked  = "session_revoked"
+)
+
+// SysTokenRevocation token 吊销记录
+// TokenID 不为空时吊销单个 token；SessionID 不为空时吊销该会话的全部 token；
+// 都为空时吊销该用户在 RevokedAt 之前签发的全部 token
+type SysTokenRevocation struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	TokenID   string    `json:"tokenId" gorm:"column:token_id;size:64;comment:token ID（jti），为空表示吊销用户的全部token"`
+	SessionID string    `json:"sessionId" gorm:"column:session_id;size:64;comment:会话ID，不为空表示吊销该会话的全部token"`
+	UserID    int64     `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	Reason    string    `json:"reason" gorm:"size:50;comment:吊销原因"`
+	RevokedAt time.Time `json:"revokedAt" gorm:"column:revoked_at;not null;comment:吊销时间"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"column:expires_at;not null;comment:记录失效时间（之后相关token已自然过期，可清理）"`
+}
+
+// TableName 指定表名
+func (SysTokenRevocation) TableName() string {
+	return "sys_token_revocation"
+}
@@ -4,6 +4,12 @@ import (
 	"time"
 )
 
+// 用户角色
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 // SysUser 系统用户表
 type SysUser struct {
 	ID                int64      `json:"id" gorm:"primaryKey;autoIncrement;comment:用户ID"`
@@ -12,7 +18,13 @@ type SysUser struct {
 	Name              string     `json:"name" gorm:"size:50;comment:真实姓名"`
 	Role              string     `json:"role" gorm:"size:50;default:'user';comment:角色：admin/user"`
 	IsDefaultPassword int8       `json:"isDefaultPassword" gorm:"column:is_default_password;type:tinyint(1);default:1;comment:是否使用默认密码：1是，0否"`
+	PasswordChangedAt *time.Time `json:"passwordChangedAt" gorm:"column:password_changed_at;comment:密码最后修改时间"`
+	TotpSecret        string     `json:"-" gorm:"column:totp_secret;size:255;serializer:encrypted;comment:TOTP密钥（Base32，加密存储）"`
+	TotpEnabled       int8       `json:"totpEnabled" gorm:"column:totp_enabled;type:tinyint(1);default:0;comment:是否启用双因素认证：1是，0否"`
+	TotpLastStep      int64      `json:"-" gorm:"column:totp_last_step;default:0;comment:最近一次验证通过的TOTP时间步（防止验证码重放）"`
 	Status            int8       `json:"status" gorm:"type:tinyint(1);default:1;comment:账号状态：1启用，0禁用"`
+	FailedLoginCount  int        `json:"failedLoginCount" gorm:"column:failed_login_count;default:0;comment:连续登录失败次数"`
+	LockedUntil       *time.Time `json:"lockedUntil" gorm:"column:locked_until;comment:锁定截止时间"`
 	LastLoginTime     *time.Time `json:"lastLoginTime" gorm:"column:last_login_time;comment:最后登录时间"`
 	CreatedAt         time.Time  `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP;comment:创建时间"`
 	UpdatedAt         time.Time  `json:"updatedAt" gorm:"column:updated_at;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间"`
@@ -23,10 +35,15 @@ func (SysUser) TableName() string {
 	return "sys_user"
 }
 
+// IsLocked 账号当前是否处于锁定状态
+func (u *SysUser) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
+}
+
 // ToUserInfo 转换为用户信息（用于返回给前端，不包含敏感信息）
 func (u *SysUser) ToUserInfo() map[string]interface{} {
 	avatar := ""
-	if u.Role == "admin" {
+	if u.Role == RoleAdmin {
 		avatar = "https://gw.alipayobjects.com/zos/antfincdn/XAosXuNZyF/BiazfanxmamNRoxxVxka.png"
 	} else {
 		avatar = "https://gw.alipayobjects.com/zos/rmsportal/BiazfanxmamNRoxxVxka.png"
@@ -38,6 +55,12 @@ func (u *SysUser) ToUserInfo() map[string]interface{} {
 		lastLoginTime = u.LastLoginTime.Format("2006-01-02 15:04:05")
 	}
 
+	// 处理锁定截止时间（未锁定时为空）
+	lockedUntil := ""
+	if u.IsLocked() {
+		lockedUntil = u.LockedUntil.Format("2006-01-02 15:04:05")
+	}
+
 	return map[string]interface{}{
 		"id":                u.ID,
 		"username":          u.Username,
@@ -45,8 +68,12 @@ func (u *SysUser) ToUserInfo() map[string]interface{} {
 		"role":              u.Role,
 		"status":            u.Status,
 		"isDefaultPassword": u.IsDefaultPassword == 1,
+		"totpEnabled":       u.TotpEnabled == 1,
 		"avatar":            avatar,
 		"createdAt":         u.CreatedAt.Format("2006-01-02 15:04:05"),
 		"lastLoginTime":     lastLoginTime,
+		"locked":            u.IsLocked(),
+		"lockedUntil":       lockedUntil,
+		"failedLoginCount":  u.FailedLoginCount,
 	}
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// SysUserSession 登录会话（设备）
+// 每次登录创建一条记录，SessionID 与刷新token的 FamilyID 相同；刷新token时更新最后活跃时间及过期时间
+type SysUserSession struct {
+	ID         int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SessionID  string     `json:"-" gorm:"column:session_id;size:64;not null;unique;comment:会话ID（刷新token的FamilyID）"`
+	UserID     int64      `json:"userId" gorm:"column:user_id;not null;comment:用户ID"`
+	IP         string     `json:"ip" gorm:"column:ip;size:64;comment:登录IP"`
+	UserAgent  string     `json:"userAgent" gorm:"column:user_agent;size:512;comment:客户端User-Agent"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"column:created_at;default:CURRENT_TIMESTAMP;comment:登录时间"`
+	LastSeenAt time.Time  `json:"lastSeenAt" gorm:"column:last_seen_at;not null;comment:最后活跃时间"`
+	LastSeenIP string     `json:"lastSeenIp" gorm:"column:last_seen_ip;size:64;comment:最后活跃IP"`
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"column:expires_at;not null;comment:过期时间（刷新token过期时间）"`
+	RevokedAt  *time.Time `json:"revokedAt" gorm:"column:revoked_at;comment:吊销时间"`
+}
+
+// TableName 指定表名
+func (SysUserSession) TableName() string {
+	return "sys_user_session"
+}
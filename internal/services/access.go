@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// errNoBuildingAccess 访问了权限范围外的楼栋
+var errNoBuildingAccess = errors.New("无权访问该楼栋")
+
+// UserAccess 用户的权限及楼栋范围
+// 为 nil 时表示系统内部调用，不做限制
+type UserAccess struct {
+	UserID       int64    `json:"userId"`
+	Role         string   `json:"role"`
+	Permissions  []string `json:"permissions"`
+	AllBuildings bool     `json:"allBuildings"`
+	Buildings    []string `json:"buildings"`
+}
+
+// HasPermission 是否拥有指定权限
+func (a *UserAccess) HasPermission(permission string) bool {
+	if a == nil {
+		return true
+	}
+	return slices.Contains(a.Permissions, permission)
+}
+
+// CanAccessBuilding 是否可以访问指定楼栋
+func (a *UserAccess) CanAccessBuilding(buildingNumber string) bool {
+	if a == nil || a.AllBuildings {
+		return true
+	}
+	return slices.Contains(a.Buildings, buildingNumber)
+}
+
+// scopeQuery 为人员查询添加楼栋范围条件
+func (a *UserAccess) scopeQuery(query *gorm.DB) *gorm.DB {
+	return a.scopeColumn(query, "building_number")
+}
+
+// scopeColumn 按指定的楼号列添加楼栋范围条件（用于多表查询）
+func (a *UserAccess) scopeColumn(query *gorm.DB, column string) *gorm.DB {
+	if a == nil || a.AllBuildings {
+		return query
+	}
+	if len(a.Buildings) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where(column+" IN ?", a.Buildings)
+}
+
+// scopeSQL 原生SQL的楼栋范围条件，返回以 AND 开头的条件及参数
+func (a *UserAccess) scopeSQL() (string, []interface{}) {
+	if a == nil || a.AllBuildings {
+		return "", nil
+	}
+	if len(a.Buildings) == 0 {
+		return " AND 1 = 0", nil
+	}
+	return " AND building_number IN ?", []interface{}{a.Buildings}
+}
+
+// AccessService 角色权限服务
+type AccessService struct {
+	db *gorm.DB
+}
+
+func NewAccessService(db *gorm.DB) *AccessService {
+	return &AccessService{db: db}
+}
+
+// GetUserAccess 获取用户的权限及楼栋范围
+// admin 角色固定拥有全部权限和全部楼栋；其他角色按 sys_role、sys_role_permission、sys_user_building 配置
+func (s *AccessService) GetUserAccess(userID int64, role string) (*UserAccess, error) {
+	access := &UserAccess{UserID: userID, Role: role, Permissions: []string{}, Buildings: []string{}}
+	if role == models.RoleAdmin {
+		access.Permissions = slices.Clone(models.AllPermissions)
+		access.AllBuildings = true
+		return access, nil
+	}
+
+	var sysRole models.SysRole
+	if err := s.db.Where("code = ?", role).First(&sysRole).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 未配置的角色没有任何权限
+			return access, nil
+		}
+		return nil, err
+	}
+	access.AllBuildings = sysRole.AllBuildings == 1
+
+	if err := s.db.Model(&models.SysRolePermission{}).Where("role_code = ?", role).
+		Order("id").Pluck("permission", &access.Permissions).Error; err != nil {
+		return nil, err
+	}
+	if !access.AllBuildings {
+		buildings, err := s.GetUserBuildings(userID)
+		if err != nil {
+			return nil, err
+		}
+		access.Buildings = buildings
+	}
+	return access, nil
+}
+
+// RoleInfo 角色及其权限
+type RoleInfo struct {
+	models.SysRole
+	Permissions []string `json:"permissions"`
+}
+
+// ListRoles 获取全部角色及权限
+func (s *AccessService) ListRoles() ([]RoleInfo, error) {
+	var roles []models.SysRole
+	if err := s.db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	var permissions []models.SysRolePermission
+	if err := s.db.Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleInfo, 0, len(roles))
+	for _, role := range roles {
+		info := RoleInfo{SysRole: role, Permissions: []string{}}
+		if role.Code == models.RoleAdmin {
+			info.Permissions = slices.Clone(models.AllPermissions)
+		} else {
+			for _, p := range permissions {
+				if p.RoleCode == role.Code {
+					info.Permissions = append(info.Permissions, p.Permission)
+				}
+			}
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// SaveRoleRequest 保存角色请求
+type SaveRoleRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description"`
+	AllBuildings bool     `json:"allBuildings"`
+	Permissions  []string `json:"permissions"`
+}
+
+// SaveRole 新增或更新角色及其权限（admin 角色不可修改）
+func (s *AccessService) SaveRole(code string, req *SaveRoleRequest) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return errors.New("角色编码不能为空")
+	}
+	if code == models.RoleAdmin {
+		return errors.New("管理员角色不可修改")
+	}
+	for _, p := range req.Permissions {
+		if !slices.Contains(models.AllPermissions, p) {
+			return errors.New("未知的权限: " + p)
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var role models.SysRole
+		err := tx.Where("code = ?", code).First(&role).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		role.Code = code
+		role.Name = req.Name
+		role.Description = req.Description
+		role.AllBuildings = 0
+		if req.AllBuildings {
+			role.AllBuildings = 1
+		}
+		if err := tx.Save(&role).Error; err != nil {
+			return errors.New("保存角色失败: " + err.Error())
+		}
+
+		if err := tx.Where("role_code = ?", code).Delete(&models.SysRolePermission{}).Error; err != nil {
+			return err
+		}
+		for _, p := range req.Permissions {
+			if err := tx.Create(&models.SysRolePermission{RoleCode: code, Permission: p}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// roleExists 检查角色是否存在
+func (s *AccessService) roleExists(code string) (bool, error) {
+	if code == models.RoleAdmin {
+		return true, nil
+	}
+	var count int64
+	if err := s.db.Model(&models.SysRole{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetUserBuildings 获取用户负责的楼栋
+func (s *AccessService) GetUserBuildings(userID int64) ([]string, error) {
+	buildings := []string{}
+	err := s.db.Model(&models.SysUserBuilding{}).Where("user_id = ?", userID).
+		Order("id").Pluck("building_number", &buildings).Error
+	return buildings, err
+}
+
+// SetUserBuildings 设置用户负责的楼栋（整体覆盖）
+func (s *AccessService) SetUserBuildings(userID int64, buildings []string) error {
+	var count int64
+	if err := s.db.Model(&models.SysUser{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("用户不存在")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.SysUserBuilding{}).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, building := range buildings {
+			building = strings.TrimSpace(building)
+			if building == "" || seen[building] {
+				continue
+			}
+			seen[building] = true
+			if err := tx.Create(&models.SysUserBuilding{UserID: userID, BuildingNumber: building}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUserAccessScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		access     *UserAccess
+		building   string
+		wantAccess bool
+		wantCond   string
+		wantParams []interface{}
+	}{
+		{name: "系统内部调用", access: nil, building: "1", wantAccess: true},
+		{name: "全部楼栋", access: &UserAccess{AllBuildings: true}, building: "1", wantAccess: true},
+		{
+			name:       "范围内的楼栋",
+			access:     &UserAccess{Buildings: []string{"1", "2"}},
+			building:   "2",
+			wantAccess: true,
+			wantCond:   " AND building_number IN ?",
+			wantParams: []interface{}{[]string{"1", "2"}},
+		},
+		{
+			name:       "范围外的楼栋",
+			access:     &UserAccess{Buildings: []string{"1", "2"}},
+			building:   "3",
+			wantCond:   " AND building_number IN ?",
+			wantParams: []interface{}{[]string{"1", "2"}},
+		},
+		{name: "未分配楼栋", access: &UserAccess{Buildings: []string{}}, building: "1", wantCond: " AND 1 = 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.CanAccessBuilding(tt.building); got != tt.wantAccess {
+				t.Errorf("CanAccessBuilding(%q) = %v, want %v", tt.building, got, tt.wantAccess)
+			}
+			cond, params := tt.access.scopeSQL()
+			if cond != tt.wantCond || !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("scopeSQL() = %q, %v, want %q, %v", cond, params, tt.wantCond, tt.wantParams)
+			}
+		})
+	}
+}
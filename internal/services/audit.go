@@ -0,0 +1,126 @@
+package services
+
+import (
+	"time"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// auditPersonBatchSize 批量写入审计日志涉及人员的批次大小
+const auditPersonBatchSize = 500
+
+// AuditService 审计日志服务
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record 保存审计日志及涉及的人员
+func (s *AuditService) Record(entry *models.AuditLog) error {
+	entry.PersonNum = len(entry.PersonIDs)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		if len(entry.PersonIDs) == 0 {
+			return nil
+		}
+		persons := make([]models.AuditLogPerson, 0, len(entry.PersonIDs))
+		for _, personID := range entry.PersonIDs {
+			persons = append(persons, models.AuditLogPerson{AuditID: entry.ID, PersonID: personID})
+		}
+		return tx.CreateInBatches(persons, auditPersonBatchSize).Error
+	})
+}
+
+// AuditLogQuery 审计日志查询条件
+type AuditLogQuery struct {
+	UserID    int64      `form:"userId"`
+	Username  string     `form:"username"`
+	Action    string     `form:"action"`
+	Resource  string     `form:"resource"`
+	PersonID  int64      `form:"personId"` // 查询访问或修改过某人员的记录
+	Endpoint  string     `form:"endpoint"`
+	StartTime *time.Time `form:"startTime" time_format:"2006-01-02 15:04:05"`
+	EndTime   *time.Time `form:"endTime" time_format:"2006-01-02 15:04:05"`
+	Page      int        `form:"page"`
+	PageSize  int        `form:"pageSize"`
+}
+
+// GetAuditLogs 分页查询审计日志（按时间倒序）
+func (s *AuditService) GetAuditLogs(query *AuditLogQuery) ([]models.AuditLog, int64, error) {
+	filtered := func() *gorm.DB {
+		db := s.db.Model(&models.AuditLog{})
+		if query.UserID > 0 {
+			db = db.Where("user_id = ?", query.UserID)
+		}
+		if query.Username != "" {
+			db = db.Where("username = ?", query.Username)
+		}
+		if query.Action != "" {
+			db = db.Where("action = ?", query.Action)
+		}
+		if query.Resource != "" {
+			db = db.Where("resource = ?", query.Resource)
+		}
+		if query.PersonID > 0 {
+			db = db.Where("id IN (?)", s.db.Model(&models.AuditLogPerson{}).
+				Select("audit_id").Where("person_id = ?", query.PersonID))
+		}
+		if query.Endpoint != "" {
+			db = db.Where("endpoint LIKE ?", "%"+query.Endpoint+"%")
+		}
+		if query.StartTime != nil {
+			db = db.Where("created_at >= ?", *query.StartTime)
+		}
+		if query.EndTime != nil {
+			db = db.Where("created_at <= ?", *query.EndTime)
+		}
+		return db
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.AuditLog
+	offset := (query.Page - 1) * query.PageSize
+	if err := filtered().Order("id DESC").Offset(offset).Limit(query.PageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := s.loadPersonIDs(logs); err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// loadPersonIDs 加载审计日志涉及的人员ID
+func (s *AuditService) loadPersonIDs(logs []models.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(logs))
+	index := make(map[int64]int, len(logs))
+	for i := range logs {
+		ids = append(ids, logs[i].ID)
+		index[logs[i].ID] = i
+		logs[i].PersonIDs = []int64{}
+	}
+
+	var persons []models.AuditLogPerson
+	if err := s.db.Where("audit_id IN ?", ids).Order("id").Find(&persons).Error; err != nil {
+		return err
+	}
+	for _, p := range persons {
+		i := index[p.AuditID]
+		logs[i].PersonIDs = append(logs[i].PersonIDs, p.PersonID)
+	}
+	return nil
+}
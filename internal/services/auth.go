@@ -1,11 +1,14 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"PLMS/internal/config"
 	"PLMS/internal/models"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,6 +19,44 @@ import (
 // JWT密钥（从环境变量读取，默认为示例密钥）
 var jwtSecret []byte
 
+// 认证相关配置：token 有效期、登录失败限制（由 ConfigureAuth 按配置设置）
+var authConfig = config.AuthConfig{
+	AccessTokenTTL:   30 * time.Minute,
+	RefreshTokenTTL:  7 * 24 * time.Hour,
+	MaxLoginFailures: 5,
+	LockDuration:     15 * time.Minute,
+	MaxIPFailures:    20,
+	IPFailureWindow:  15 * time.Minute,
+}
+
+// ConfigureAuth 设置认证相关配置，服务启动时调用；未配置（为 0）的项保留默认值
+func ConfigureAuth(cfg config.AuthConfig) {
+	if cfg.AccessTokenTTL > 0 {
+		authConfig.AccessTokenTTL = cfg.AccessTokenTTL
+	}
+	if cfg.RefreshTokenTTL > 0 {
+		authConfig.RefreshTokenTTL = cfg.RefreshTokenTTL
+	}
+	if cfg.MaxLoginFailures > 0 {
+		authConfig.MaxLoginFailures = cfg.MaxLoginFailures
+	}
+	if cfg.LockDuration > 0 {
+		authConfig.LockDuration = cfg.LockDuration
+	}
+	if cfg.MaxIPFailures > 0 {
+		authConfig.MaxIPFailures = cfg.MaxIPFailures
+	}
+	if cfg.IPFailureWindow > 0 {
+		authConfig.IPFailureWindow = cfg.IPFailureWindow
+	}
+}
+
+// 登录限制错误
+var (
+	ErrLoginThrottled = errors.New("登录失败次数过多，请稍后再试")
+	ErrAccountLocked  = errors.New("账号已锁定")
+)
+
 // 初始化JWT密钥
 func init() {
 	secret := os.Getenv("JWT_SECRET")
@@ -30,6 +71,10 @@ type CustomClaims struct {
 	UserID   int64  `json:"userId"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// SessionID 登录会话ID（即刷新token的 FamilyID），登出时据此吊销刷新token
+	SessionID string `json:"sid,omitempty"`
+	// Purpose 特殊用途token（如双因素认证待验证），不能用于访问接口
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -52,46 +97,112 @@ type LoginRequest struct {
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
 	OldPassword string `json:"oldPassword" binding:"required"`
-	NewPassword string `json:"newPassword" binding:"required,min=6"`
+	NewPassword string `json:"newPassword" binding:"required"` // 长度等要求由密码策略校验
 }
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token string                 `json:"token"`
-	User  map[string]interface{} `json:"user"`
+	Token        string                 `json:"token"`
+	RefreshToken string                 `json:"refreshToken"`
+	ExpiresIn    int64                  `json:"expiresIn"` // 访问token有效期（秒）
+	User         map[string]interface{} `json:"user"`
+	// 启用双因素认证时，密码验证通过后只返回以下字段，需提交验证码完成登录
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	PendingToken      string `json:"pendingToken,omitempty"`
 }
 
 // Login 用户登录
-func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
+// 同一IP失败次数过多时拒绝登录；账号连续失败达到上限后锁定一段时间
+func (s *AuthService) Login(req *LoginRequest, client ClientInfo) (*LoginResponse, error) {
+	if ipLoginThrottle.blocked(client.IP) {
+		return nil, ErrLoginThrottled
+	}
+
 	// 查询用户
 	var user models.SysUser
 	if err := s.db.Where("username = ? AND status = 1", req.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ipLoginThrottle.fail(client.IP)
 			return nil, errors.New("用户名或密码错误")
 		}
 		return nil, err
 	}
 
+	if user.IsLocked() {
+		return nil, fmt.Errorf("%w，请于 %s 后重试", ErrAccountLocked, user.LockedUntil.Format("2006-01-02 15:04:05"))
+	}
+
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("用户名或密码错误")
+		ipLoginThrottle.fail(client.IP)
+		return nil, s.recordLoginFailure(&user, "用户名或密码错误")
 	}
 
-	// 更新最后登录时间
+	// 已启用双因素认证：签发待验证token，验证码校验通过后再完成登录
+	if user.TotpEnabled == 1 {
+		pendingToken, err := generatePendingToken(&user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{TwoFactorRequired: true, PendingToken: pendingToken}, nil
+	}
+
+	return s.completeLogin(&user, client)
+}
+
+// completeLogin 登录验证全部通过：更新最后登录时间，清除失败次数，签发token
+func (s *AuthService) completeLogin(user *models.SysUser, client ClientInfo) (*LoginResponse, error) {
 	now := time.Now()
 	user.LastLoginTime = &now
-	s.db.Model(&user).Update("last_login_time", now)
+	user.FailedLoginCount = 0
+	user.LockedUntil = nil
+	s.db.Model(user).Updates(map[string]interface{}{
+		"last_login_time":    now,
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	})
 
-	// 生成JWT token
-	token, err := GenerateToken(user.ID, user.Username, user.Role)
+	// 生成访问token及刷新token
+	familyID, err := newTokenID()
 	if err != nil {
 		return nil, err
 	}
+	return s.issueTokens(user, familyID, client)
+}
+
+// recordLoginFailure 记录账号登录失败，达到上限时锁定账号
+// message 为失败原因，返回给调用方的错误信息中附带剩余次数
+func (s *AuthService) recordLoginFailure(user *models.SysUser, message string) error {
+	if err := s.db.Model(user).UpdateColumn("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+		return err
+	}
+	if err := s.db.Select("failed_login_count").First(user, user.ID).Error; err != nil {
+		return err
+	}
 
-	return &LoginResponse{
-		Token: token,
-		User:  user.ToUserInfo(),
-	}, nil
+	if user.FailedLoginCount < authConfig.MaxLoginFailures {
+		return fmt.Errorf("%s，再失败 %d 次账号将被锁定", message, authConfig.MaxLoginFailures-user.FailedLoginCount)
+	}
+
+	lockedUntil := time.Now().Add(authConfig.LockDuration)
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       lockedUntil,
+	}).Error; err != nil {
+		return err
+	}
+	return fmt.Errorf("%w，请于 %s 后重试", ErrAccountLocked, lockedUntil.Format("2006-01-02 15:04:05"))
+}
+
+// UnlockUser 解除账号锁定并清除失败次数（管理员接口）
+func (s *AuthService) UnlockUser(userID int64) error {
+	if _, err := s.GetUserByID(userID); err != nil {
+		return errors.New("用户不存在")
+	}
+	return s.db.Model(&models.SysUser{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}).Error
 }
 
 // GetUserByID 根据ID获取用户信息
@@ -116,36 +227,68 @@ func (s *AuthService) ChangePassword(userID int64, req *ChangePasswordRequest) e
 		return errors.New("旧密码错误")
 	}
 
+	// 校验密码策略
+	if err := s.checkPasswordPolicy(user.ID, user.Username, req.NewPassword); err != nil {
+		return err
+	}
+
 	// 加密新密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return errors.New("密码加密失败")
 	}
 
-	// 更新密码和默认密码标志
-	return s.db.Model(&user).Updates(map[string]interface{}{
-		"password":            string(hashedPassword),
-		"is_default_password": 0,
-	}).Error
+	// 更新密码和默认密码标志，记录历史密码
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password":            string(hashedPassword),
+			"is_default_password": 0,
+			"password_changed_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return s.savePasswordHistory(tx, user.ID, string(hashedPassword))
+	}); err != nil {
+		return err
+	}
+
+	// 修改密码后吊销已签发的全部 token，需重新登录
+	return s.RevokeUserTokens(userID, models.RevokeReasonPasswordChanged)
 }
 
 // IsDefaultPassword 检查是否使用默认密码
 func (s *AuthService) IsDefaultPassword(userID int64) (bool, error) {
-	var user models.SysUser
-	if err := s.db.First(&user, userID).Error; err != nil {
+	status, err := s.GetPasswordStatus(userID)
+	if err != nil {
 		return false, err
 	}
-	return user.IsDefaultPassword == 1, nil
+	return status.IsDefaultPassword, nil
+}
+
+// newTokenID 生成随机的 token ID（jti），用于吊销单个 token
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID int64, username, role string) (string, error) {
+// GenerateToken 生成JWT访问token
+// sessionID 为登录会话ID，可为空
+func GenerateToken(userID int64, username, role, sessionID string) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
 	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24小时过期
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(authConfig.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   fmt.Sprintf("%d", userID),
 		},
@@ -184,22 +327,43 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// checkRole 检查角色是否存在
+func (s *AuthService) checkRole(role string) error {
+	exists, err := NewAccessService(s.db).roleExists(role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("角色不存在: " + role)
+	}
+	return nil
+}
+
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // 长度等要求由密码策略校验
 	Name     string `json:"name" binding:"required"`
-	Role     string `json:"role" binding:"required,oneof=admin user"`
+	Role     string `json:"role" binding:"required"`
 }
 
 // CreateUser 创建新用户
 func (s *AuthService) CreateUser(req *CreateUserRequest) (*models.SysUser, error) {
+	if err := s.checkRole(req.Role); err != nil {
+		return nil, err
+	}
+
 	// 检查用户名是否已存在
 	var existingUser models.SysUser
 	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return nil, errors.New("用户名已存在")
 	}
 
+	// 校验密码策略
+	if err := s.checkPasswordPolicy(0, req.Username, req.Password); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
 	hashedPassword, err := HashPassword(req.Password)
 	if err != nil {
@@ -207,17 +371,24 @@ func (s *AuthService) CreateUser(req *CreateUserRequest) (*models.SysUser, error
 	}
 
 	// 创建用户
+	now := time.Now()
 	user := &models.SysUser{
 		Username:          req.Username,
 		Password:          hashedPassword,
 		Name:              req.Name,
 		Role:              req.Role,
 		IsDefaultPassword: 0, // 新用户不是默认密码
+		PasswordChangedAt: &now,
 		Status:            1, // 默认启用
 	}
 
-	if err := s.db.Create(user).Error; err != nil {
-		return nil, errors.New("创建用户失败: " + err.Error())
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return errors.New("创建用户失败: " + err.Error())
+		}
+		return s.savePasswordHistory(tx, user.ID, hashedPassword)
+	}); err != nil {
+		return nil, err
 	}
 
 	return user, nil
@@ -232,42 +403,80 @@ func (s *AuthService) DeleteUser(userID int64) error {
 	if result.RowsAffected == 0 {
 		return errors.New("用户不存在")
 	}
-	return nil
+	return s.RevokeUserTokens(userID, models.RevokeReasonUserDisabled)
+}
+
+// ForceLogout 强制用户在所有设备下线
+func (s *AuthService) ForceLogout(userID int64) error {
+	if _, err := s.GetUserByID(userID); err != nil {
+		return errors.New("用户不存在")
+	}
+	return s.RevokeUserTokens(userID, models.RevokeReasonForceLogout)
 }
 
 // UpdateUser 更新用户信息
 type UpdateUserRequest struct {
 	Name     string `json:"name" binding:"required"`
-	Role     string `json:"role" binding:"required,oneof=admin user"`
-	Status   int8   `json:"status" binding:"omitempty,oneof=0 1"`
-	Password string `json:"password,omitempty" binding:"omitempty,min=6"`
+	Role     string `json:"role" binding:"required"`
+	Status   *int8  `json:"status" binding:"omitempty,oneof=0 1"` // 为空表示不修改
+	Password string `json:"password,omitempty"`                   // 为空表示不修改；长度等要求由密码策略校验
 }
 
 func (s *AuthService) UpdateUser(userID int64, req *UpdateUserRequest) error {
+	if err := s.checkRole(req.Role); err != nil {
+		return err
+	}
+
 	updates := map[string]interface{}{
 		"name": req.Name,
 		"role": req.Role,
 	}
 	// 如果传了 status，也更新状态
-	if req.Status == 0 || req.Status == 1 {
-		updates["status"] = req.Status
+	if req.Status != nil {
+		updates["status"] = *req.Status
 	}
-	// 如果传了密码，加密后更新
+
+	existing, err := s.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	oldStatus, oldRole := existing.Status, existing.Role
+
+	// 如果传了密码，校验密码策略并加密后更新
+	hashedPassword := ""
 	if req.Password != "" {
-		hashedPassword, err := HashPassword(req.Password)
+		if err := s.checkPasswordPolicy(userID, existing.Username, req.Password); err != nil {
+			return err
+		}
+		hashedPassword, err = HashPassword(req.Password)
 		if err != nil {
 			return errors.New("密码加密失败")
 		}
 		updates["password"] = hashedPassword
 		updates["is_default_password"] = 0 // 修改密码后不再是默认密码
+		updates["password_changed_at"] = time.Now()
 	}
 
-	result := s.db.Model(&models.SysUser{}).Where("id = ?", userID).Updates(updates)
-	if result.Error != nil {
-		return result.Error
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if hashedPassword != "" {
+			return s.savePasswordHistory(tx, userID, hashedPassword)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("用户不存在")
+
+	// 禁用、重置密码或变更角色后，已签发的 token 失效
+	switch {
+	case req.Status != nil && *req.Status == 0 && oldStatus != 0:
+		return s.RevokeUserTokens(userID, models.RevokeReasonUserDisabled)
+	case req.Password != "":
+		return s.RevokeUserTokens(userID, models.RevokeReasonPasswordChanged)
+	case req.Role != oldRole:
+		return s.RevokeUserTokens(userID, models.RevokeReasonUserUpdated)
 	}
 	return nil
 }
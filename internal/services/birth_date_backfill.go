@@ -0,0 +1,73 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"PLMS/internal/idcard"
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// birthDateBatchSize 补充出生日期时每批处理的人员数
+const birthDateBatchSize = 500
+
+var birthDateBackfillOnce sync.Once
+
+// StartBirthDateBackfill 启动时在后台为有身份证号但没有出生日期的人员补充出生日期（只执行一次）
+// 年龄不再单独维护：有出生日期时查询时按出生日期计算（AfterFind、personAgeSQL），
+// age 列只保存出生日期未知时录入的年龄
+func StartBirthDateBackfill(db *gorm.DB) {
+	birthDateBackfillOnce.Do(func() {
+		go func() {
+			filled, err := fillBirthDates(db)
+			if err != nil {
+				log.Printf("补充出生日期失败: %v", err)
+				return
+			}
+			log.Printf("补充出生日期完成：%d 人", filled)
+		}()
+	})
+}
+
+// fillBirthDates 为有身份证号但没有出生日期的人员补充出生日期
+// 身份证号加密存储，需要读出后在程序中解析；身份证号无效的人员标记为已检查，之后启动时不再重复解析
+func fillBirthDates(db *gorm.DB) (int, error) {
+	var lastID int64
+	filled := 0
+	for {
+		var persons []models.Person
+		if err := db.Select("id, id_card").
+			Where("id > ? AND birth_date IS NULL AND birth_date_checked = 0 AND id_card_bidx <> ''", lastID).
+			Order("id").Limit(birthDateBatchSize).Find(&persons).Error; err != nil {
+			return filled, err
+		}
+		if len(persons) == 0 {
+			return filled, nil
+		}
+		var invalidIDs []int64
+		for _, person := range persons {
+			info, err := idcard.Parse(person.IDCard)
+			if err != nil {
+				invalidIDs = append(invalidIDs, person.ID)
+				continue
+			}
+			if err := db.Model(&models.Person{}).Where("id = ?", person.ID).UpdateColumns(map[string]interface{}{
+				"birth_date":         info.BirthDate.Format("2006-01-02"),
+				"birth_date_checked": 1,
+				"gender":             info.Gender,
+			}).Error; err != nil {
+				return filled, err
+			}
+			filled++
+		}
+		if len(invalidIDs) > 0 {
+			if err := db.Model(&models.Person{}).Where("id IN ?", invalidIDs).
+				UpdateColumn("birth_date_checked", 1).Error; err != nil {
+				return filled, err
+			}
+		}
+		lastID = persons[len(persons)-1].ID
+	}
+}
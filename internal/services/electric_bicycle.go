@@ -0,0 +1,276 @@
+package services
+
+import (
+	"PLMS/internal/models"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type ElectricBicycleService struct {
+	db     *gorm.DB
+	access *UserAccess // 当前用户的权限范围，nil 表示不限制
+}
+
+func NewElectricBicycleService(db *gorm.DB) *ElectricBicycleService {
+	return &ElectricBicycleService{db: db}
+}
+
+// WithAccess 返回按指定用户权限范围操作的服务（只能访问范围内人员的电动车）
+func (s *ElectricBicycleService) WithAccess(access *UserAccess) *ElectricBicycleService {
+	return &ElectricBicycleService{db: s.db, access: access}
+}
+
+// normalizePlateNumber 统一车牌号格式（去空格、转大写）
+func normalizePlateNumber(plate string) string {
+	plate = strings.ReplaceAll(plate, " ", "")
+	return strings.ToUpper(strings.TrimSpace(plate))
+}
+
+// validateBicycle 校验电动车信息
+func validateBicycle(bicycle *models.ElectricBicycle) error {
+	bicycle.Model = strings.TrimSpace(bicycle.Model)
+	bicycle.PlateNumber = normalizePlateNumber(bicycle.PlateNumber)
+	if bicycle.Model == "" {
+		return errors.New("车型型号不能为空")
+	}
+	if bicycle.PlateNumber == "" {
+		return errors.New("车牌号码不能为空")
+	}
+	if len(bicycle.PlateNumber) > 20 {
+		return errors.New("车牌号码长度不能超过20位")
+	}
+	return nil
+}
+
+// checkPlateNumberUnique 检查车牌号是否已被其他未删除的电动车使用
+func checkPlateNumberUnique(tx *gorm.DB, plateNumber string, excludeID int64) error {
+	var count int64
+	query := tx.Model(&models.ElectricBicycle{}).Where("plate_number = ? AND is_del = 0", plateNumber)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("车牌号码已存在")
+	}
+	return nil
+}
+
+// syncPersonElectricCar 根据电动车表同步人员的是否有电动车、车牌号、品牌型号字段
+func syncPersonElectricCar(tx *gorm.DB, personID int64) error {
+	var bicycles []models.ElectricBicycle
+	if err := tx.Where("person_id = ? AND is_del = 0", personID).Order("id").Find(&bicycles).Error; err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"has_electric_car": 2,
+		"license_plate":    "",
+		"brand_model":      "",
+	}
+	if len(bicycles) > 0 {
+		first := bicycles[0]
+		brandModel := first.Model
+		if first.Brand != nil && *first.Brand != "" {
+			brandModel = *first.Brand + " " + first.Model
+		}
+		updates["has_electric_car"] = 1
+		updates["license_plate"] = first.PlateNumber
+		updates["brand_model"] = brandModel
+	}
+	return tx.Model(&models.Person{}).Where("id = ?", personID).Updates(updates).Error
+}
+
+// removePersonsElectricBicycles 人员被删除时软删除名下的电动车，并清空人员的电动车字段
+// 返回各人员被删除的电动车ID（导入回滚时据此恢复）
+func removePersonsElectricBicycles(tx *gorm.DB, personIDs []int64) (map[int64][]int64, error) {
+	var bicycles []models.ElectricBicycle
+	if err := tx.Select("id, person_id").Where("person_id IN ? AND is_del = 0", personIDs).
+		Order("id").Find(&bicycles).Error; err != nil {
+		return nil, err
+	}
+	removed := make(map[int64][]int64)
+	if len(bicycles) == 0 {
+		return removed, nil
+	}
+	ids := make([]int64, 0, len(bicycles))
+	for _, bicycle := range bicycles {
+		ids = append(ids, bicycle.ID)
+		removed[bicycle.PersonID] = append(removed[bicycle.PersonID], bicycle.ID)
+	}
+	if err := tx.Model(&models.ElectricBicycle{}).Where("id IN ?", ids).Update("is_del", 1).Error; err != nil {
+		return nil, err
+	}
+	personIDs = make([]int64, 0, len(removed))
+	for personID := range removed {
+		personIDs = append(personIDs, personID)
+	}
+	return removed, tx.Model(&models.Person{}).Where("id IN ?", personIDs).Updates(map[string]interface{}{
+		"has_electric_car": 2,
+		"license_plate":    "",
+		"brand_model":      "",
+	}).Error
+}
+
+// ensurePersonExists 检查人员存在、未删除且在楼栋范围内
+func ensurePersonExists(tx *gorm.DB, personID int64, access *UserAccess) error {
+	var count int64
+	if err := access.scopeQuery(tx.Model(&models.Person{})).Where("id = ? AND is_del = 0", personID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("人员不存在")
+	}
+	return nil
+}
+
+// GetBicyclesByPerson 获取人员名下的电动车
+func (s *ElectricBicycleService) GetBicyclesByPerson(personID int64) ([]models.ElectricBicycle, error) {
+	if err := ensurePersonExists(s.db, personID, s.access); err != nil {
+		return nil, err
+	}
+	var bicycles []models.ElectricBicycle
+	err := s.db.Where("person_id = ? AND is_del = 0", personID).Order("id").Find(&bicycles).Error
+	return bicycles, err
+}
+
+// GetBicycle 获取未删除的电动车（所属人员需在用户的楼栋范围内）
+func (s *ElectricBicycleService) GetBicycle(id int64) (*models.ElectricBicycle, error) {
+	var bicycle models.ElectricBicycle
+	if err := s.db.Where("id = ? AND is_del = 0", id).First(&bicycle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("电动车不存在")
+		}
+		return nil, err
+	}
+	if err := ensurePersonExists(s.db, bicycle.PersonID, s.access); err != nil {
+		return nil, err
+	}
+	return &bicycle, nil
+}
+
+// CreateBicycle 为人员新增电动车
+// 参数:
+//   - personID: 所属人员ID
+//   - bicycle: 电动车信息
+//
+// 返回值:
+//   - *models.ElectricBicycle: 保存后的电动车信息
+//   - error: 错误信息
+func (s *ElectricBicycleService) CreateBicycle(personID int64, bicycle *models.ElectricBicycle) (*models.ElectricBicycle, error) {
+	if err := validateBicycle(bicycle); err != nil {
+		return nil, err
+	}
+	bicycle.ID = 0
+	bicycle.PersonID = personID
+	bicycle.IsDel = 0
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensurePersonExists(tx, personID, s.access); err != nil {
+			return err
+		}
+		if err := checkPlateNumberUnique(tx, bicycle.PlateNumber, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(bicycle).Error; err != nil {
+			return errors.New("新增电动车失败: " + err.Error())
+		}
+		return syncPersonElectricCar(tx, personID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bicycle, nil
+}
+
+// UpdateBicycle 更新电动车信息（不可变更所属人员）
+func (s *ElectricBicycleService) UpdateBicycle(id int64, bicycle *models.ElectricBicycle) (*models.ElectricBicycle, error) {
+	if err := validateBicycle(bicycle); err != nil {
+		return nil, err
+	}
+
+	var existing models.ElectricBicycle
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND is_del = 0", id).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("电动车不存在")
+			}
+			return err
+		}
+		if err := ensurePersonExists(tx, existing.PersonID, s.access); err != nil {
+			return err
+		}
+		if err := checkPlateNumberUnique(tx, bicycle.PlateNumber, id); err != nil {
+			return err
+		}
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"model":        bicycle.Model,
+			"brand":        bicycle.Brand,
+			"color":        bicycle.Color,
+			"plate_number": bicycle.PlateNumber,
+		}).Error; err != nil {
+			return errors.New("更新电动车失败: " + err.Error())
+		}
+		return syncPersonElectricCar(tx, existing.PersonID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&existing, id).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// DeleteBicycle 删除电动车（软删除）
+func (s *ElectricBicycleService) DeleteBicycle(id int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.ElectricBicycle
+		if err := tx.Where("id = ? AND is_del = 0", id).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("电动车不存在")
+			}
+			return err
+		}
+		if err := ensurePersonExists(tx, existing.PersonID, s.access); err != nil {
+			return err
+		}
+		if err := tx.Model(&existing).Update("is_del", 1).Error; err != nil {
+			return err
+		}
+		return syncPersonElectricCar(tx, existing.PersonID)
+	})
+}
+
+// FindOwnersByPlateNumber 根据车牌号查询车主（支持部分匹配）
+func (s *ElectricBicycleService) FindOwnersByPlateNumber(plateNumber string) ([]models.ElectricBicycleOwner, error) {
+	plateNumber = normalizePlateNumber(plateNumber)
+	if plateNumber == "" {
+		return nil, errors.New("车牌号码不能为空")
+	}
+
+	var bicycles []models.ElectricBicycle
+	if err := s.db.Where("is_del = 0 AND plate_number LIKE ?", "%"+plateNumber+"%").
+		Order("id").Find(&bicycles).Error; err != nil {
+		return nil, err
+	}
+
+	owners := []models.ElectricBicycleOwner{}
+	for _, bicycle := range bicycles {
+		var person models.Person
+		if err := s.access.scopeQuery(s.db).Where("id = ? AND is_del = 0", bicycle.PersonID).First(&person).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if !s.access.HasPermission(models.PermViewSensitive) {
+			person.MaskSensitive()
+		}
+		owners = append(owners, models.ElectricBicycleOwner{Bicycle: bicycle, Person: person})
+	}
+	return owners, nil
+}
@@ -0,0 +1,535 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// roomFloorPattern 可以推算楼层的房号（三、四位数字，如 301、1502）
+var roomFloorPattern = regexp.MustCompile(`^\d{3,4}$`)
+
+// roomFloor 按房号推算楼层，无法推算时返回 0
+func roomFloor(roomNumber string) int {
+	if !roomFloorPattern.MatchString(roomNumber) {
+		return 0
+	}
+	n, _ := strconv.Atoi(roomNumber)
+	return n / 100
+}
+
+// roomResolver 按楼号、单元、房号查找房间，不存在时依次创建楼栋、单元、房间（同一次操作内缓存结果）
+type roomResolver struct {
+	tx    *gorm.DB
+	rooms map[string]int64
+}
+
+func newRoomResolver(tx *gorm.DB) *roomResolver {
+	return &roomResolver{tx: tx, rooms: make(map[string]int64)}
+}
+
+// resolve 返回房间ID；楼号、单元、房号不完整时返回 nil
+func (r *roomResolver) resolve(buildingNumber string, unitNumber int, roomNumber string) (*int64, error) {
+	buildingNumber = strings.TrimSpace(buildingNumber)
+	roomNumber = strings.TrimSpace(roomNumber)
+	if buildingNumber == "" || unitNumber <= 0 || roomNumber == "" {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s|%d|%s", buildingNumber, unitNumber, roomNumber)
+	if id, ok := r.rooms[key]; ok {
+		return &id, nil
+	}
+
+	building, err := ensureBuilding(r.tx, buildingNumber)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := ensureUnit(r.tx, building.ID, unitNumber)
+	if err != nil {
+		return nil, err
+	}
+	room, err := ensureRoom(r.tx, unit, roomNumber)
+	if err != nil {
+		return nil, err
+	}
+	r.rooms[key] = room.ID
+	return &room.ID, nil
+}
+
+// ensureBuilding 查找楼栋，不存在时创建，已删除时恢复
+func ensureBuilding(tx *gorm.DB, buildingNumber string) (*models.Building, error) {
+	var building models.Building
+	err := tx.Where("building_number = ?", buildingNumber).First(&building).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		building = models.Building{BuildingNumber: buildingNumber}
+		if err := tx.Create(&building).Error; err != nil {
+			return nil, errors.New("新增楼栋失败: " + err.Error())
+		}
+		return &building, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if building.IsDel != 0 {
+		if err := tx.Model(&building).Update("is_del", 0).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &building, nil
+}
+
+// ensureUnit 查找单元，不存在时创建，已删除时恢复
+func ensureUnit(tx *gorm.DB, buildingID int64, unitNumber int) (*models.Unit, error) {
+	var unit models.Unit
+	err := tx.Where("building_id = ? AND unit_number = ?", buildingID, unitNumber).First(&unit).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		unit = models.Unit{BuildingID: buildingID, UnitNumber: unitNumber}
+		if err := tx.Create(&unit).Error; err != nil {
+			return nil, errors.New("新增单元失败: " + err.Error())
+		}
+		return &unit, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if unit.IsDel != 0 {
+		if err := tx.Model(&unit).Update("is_del", 0).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &unit, nil
+}
+
+// ensureRoom 查找房间，不存在时创建（楼层按房号推算），已删除时恢复
+func ensureRoom(tx *gorm.DB, unit *models.Unit, roomNumber string) (*models.Room, error) {
+	var room models.Room
+	err := tx.Where("unit_id = ? AND room_number = ?", unit.ID, roomNumber).First(&room).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		room = models.Room{BuildingID: unit.BuildingID, UnitID: unit.ID, RoomNumber: roomNumber, Floor: roomFloor(roomNumber)}
+		if err := tx.Create(&room).Error; err != nil {
+			return nil, errors.New("新增房间失败: " + err.Error())
+		}
+		return &room, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if room.IsDel != 0 {
+		if err := tx.Model(&room).Update("is_del", 0).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &room, nil
+}
+
+// syncPersonRoom 按人员当前的楼号、单元、房号更新所属房间（用于按字段批量修改地址之后）
+func syncPersonRoom(tx *gorm.DB, resolver *roomResolver, personID int64) error {
+	var person models.Person
+	if err := tx.Select("id, building_number, unit_number, room_number").First(&person, personID).Error; err != nil {
+		return err
+	}
+	roomID, err := resolver.resolve(person.BuildingNumber, person.UnitNumber, person.RoomNumber)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.Person{}).Where("id = ?", personID).UpdateColumn("room_id", roomID).Error
+}
+
+// hasAddressChange 修改的字段中是否包含楼号、单元或房号
+func hasAddressChange(changes map[string]interface{}) bool {
+	for _, column := range []string{"building_number", "unit_number", "room_number"} {
+		if _, ok := changes[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HousingService 楼栋、单元、房间基础数据服务
+type HousingService struct {
+	db     *gorm.DB
+	access *UserAccess // 当前用户的权限范围，nil 表示不限制
+}
+
+// NewHousingService 创建楼栋、单元、房间服务实例
+func NewHousingService(db *gorm.DB) *HousingService {
+	return &HousingService{db: db}
+}
+
+// WithAccess 返回按指定用户权限范围操作的服务
+func (s *HousingService) WithAccess(access *UserAccess) *HousingService {
+	return &HousingService{db: s.db, access: access}
+}
+
+// RoomInfo 房间及所在楼栋、单元、住户数
+type RoomInfo struct {
+	models.Room
+	BuildingNumber string `json:"building_number"`
+	UnitNumber     int    `json:"unit_number"`
+	PersonNum      int    `json:"person_num"` // 未删除的住户数，0 表示空置
+}
+
+// GetBuildings 获取楼栋列表（限制在用户的楼栋范围内）
+func (s *HousingService) GetBuildings() ([]models.Building, error) {
+	var buildings []models.Building
+	err := s.access.scopeQuery(s.db).Where("is_del = 0").
+		Order("CAST(building_number AS UNSIGNED), building_number").
+		Find(&buildings).Error
+	return buildings, err
+}
+
+// getBuilding 获取未删除的楼栋（需在用户的楼栋范围内）
+func (s *HousingService) getBuilding(tx *gorm.DB, id int64) (*models.Building, error) {
+	var building models.Building
+	if err := tx.Where("id = ? AND is_del = 0", id).First(&building).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("楼栋不存在")
+		}
+		return nil, err
+	}
+	if !s.access.CanAccessBuilding(building.BuildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+	return &building, nil
+}
+
+// GetBuilding 获取未删除的楼栋
+func (s *HousingService) GetBuilding(id int64) (*models.Building, error) {
+	return s.getBuilding(s.db, id)
+}
+
+// validateBuilding 校验楼栋信息
+func validateBuilding(building *models.Building) error {
+	building.BuildingNumber = strings.TrimSpace(building.BuildingNumber)
+	building.Name = strings.TrimSpace(building.Name)
+	if building.FloorCount < 0 {
+		return errors.New("总层数不能小于0")
+	}
+	return nil
+}
+
+// CreateBuilding 新增楼栋（楼号不可重复，已删除的同号楼栋恢复后更新）
+func (s *HousingService) CreateBuilding(building *models.Building) (*models.Building, error) {
+	if err := validateBuilding(building); err != nil {
+		return nil, err
+	}
+	if building.BuildingNumber == "" {
+		return nil, errors.New("楼号不能为空")
+	}
+	if !s.access.CanAccessBuilding(building.BuildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+
+	var saved *models.Building
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Building
+		err := tx.Where("building_number = ?", building.BuildingNumber).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && existing.IsDel == 0 {
+			return errors.New("楼号已存在")
+		}
+		if existing.ID == 0 {
+			building.ID = 0
+			building.IsDel = 0
+			if err := tx.Create(building).Error; err != nil {
+				return errors.New("新增楼栋失败: " + err.Error())
+			}
+			saved = building
+			return nil
+		}
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"name":        building.Name,
+			"floor_count": building.FloorCount,
+			"remark":      building.Remark,
+			"is_del":      0,
+		}).Error; err != nil {
+			return err
+		}
+		saved = &existing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBuilding(saved.ID)
+}
+
+// UpdateBuilding 更新楼栋信息（楼号与用户的楼栋权限关联，不可修改）
+func (s *HousingService) UpdateBuilding(id int64, building *models.Building) (*models.Building, error) {
+	if err := validateBuilding(building); err != nil {
+		return nil, err
+	}
+	existing, err := s.GetBuilding(id)
+	if err != nil {
+		return nil, err
+	}
+	if building.BuildingNumber != "" && building.BuildingNumber != existing.BuildingNumber {
+		return nil, errors.New("楼号不可修改")
+	}
+	if err := s.db.Model(existing).Updates(map[string]interface{}{
+		"name":        building.Name,
+		"floor_count": building.FloorCount,
+		"remark":      building.Remark,
+	}).Error; err != nil {
+		return nil, errors.New("更新楼栋失败: " + err.Error())
+	}
+	return s.GetBuilding(id)
+}
+
+// DeleteBuilding 删除楼栋（软删除，楼栋下没有单元时才可删除）
+func (s *HousingService) DeleteBuilding(id int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		building, err := s.getBuilding(tx, id)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Unit{}).Where("building_id = ? AND is_del = 0", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("楼栋下还有单元，不能删除")
+		}
+		return tx.Model(building).Update("is_del", 1).Error
+	})
+}
+
+// GetUnits 获取楼栋下的单元
+func (s *HousingService) GetUnits(buildingID int64) ([]models.Unit, error) {
+	if _, err := s.GetBuilding(buildingID); err != nil {
+		return nil, err
+	}
+	var units []models.Unit
+	err := s.db.Where("building_id = ? AND is_del = 0", buildingID).Order("unit_number").Find(&units).Error
+	return units, err
+}
+
+// getUnit 获取未删除的单元及所属楼栋（楼栋需在用户的楼栋范围内）
+func (s *HousingService) getUnit(tx *gorm.DB, id int64) (*models.Unit, *models.Building, error) {
+	var unit models.Unit
+	if err := tx.Where("id = ? AND is_del = 0", id).First(&unit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("单元不存在")
+		}
+		return nil, nil, err
+	}
+	building, err := s.getBuilding(tx, unit.BuildingID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &unit, building, nil
+}
+
+// GetUnit 获取未删除的单元
+func (s *HousingService) GetUnit(id int64) (*models.Unit, error) {
+	unit, _, err := s.getUnit(s.db, id)
+	return unit, err
+}
+
+// CreateUnit 在楼栋下新增单元（单元号不可重复，已删除的同号单元恢复）
+func (s *HousingService) CreateUnit(buildingID int64, unit *models.Unit) (*models.Unit, error) {
+	if unit.UnitNumber <= 0 {
+		return nil, errors.New("单元号必须大于0")
+	}
+	var saved *models.Unit
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.getBuilding(tx, buildingID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Unit{}).Where("building_id = ? AND unit_number = ? AND is_del = 0", buildingID, unit.UnitNumber).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("单元已存在")
+		}
+		var err error
+		saved, err = ensureUnit(tx, buildingID, unit.UnitNumber)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUnit(saved.ID)
+}
+
+// DeleteUnit 删除单元（软删除，单元下没有房间时才可删除）
+func (s *HousingService) DeleteUnit(id int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		unit, _, err := s.getUnit(tx, id)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Room{}).Where("unit_id = ? AND is_del = 0", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("单元下还有房间，不能删除")
+		}
+		return tx.Model(unit).Update("is_del", 1).Error
+	})
+}
+
+// roomInfoQuery 房间及所在楼栋、单元、住户数查询
+func (s *HousingService) roomInfoQuery() *gorm.DB {
+	return s.db.Table("room").
+		Select("room.*, building.building_number, unit.unit_number, " +
+			"(SELECT COUNT(*) FROM person WHERE person.room_id = room.id AND person.is_del = 0) AS person_num").
+		Joins("JOIN unit ON unit.id = room.unit_id").
+		Joins("JOIN building ON building.id = room.building_id").
+		Where("room.is_del = 0")
+}
+
+// GetRooms 获取单元下的房间（包括没有住户的房间）
+func (s *HousingService) GetRooms(unitID int64) ([]RoomInfo, error) {
+	if _, err := s.GetUnit(unitID); err != nil {
+		return nil, err
+	}
+	var rooms []RoomInfo
+	err := s.roomInfoQuery().Where("room.unit_id = ?", unitID).
+		Order("CAST(room.room_number AS UNSIGNED), room.room_number").
+		Scan(&rooms).Error
+	return rooms, err
+}
+
+// GetRoom 获取未删除的房间（所在楼栋需在用户的楼栋范围内）
+func (s *HousingService) GetRoom(id int64) (*RoomInfo, error) {
+	var room RoomInfo
+	result := s.roomInfoQuery().Where("room.id = ?", id).Scan(&room)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("房间不存在")
+	}
+	if !s.access.CanAccessBuilding(room.BuildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+	return &room, nil
+}
+
+// validateRoom 校验房间信息
+func validateRoom(room *models.Room) error {
+	room.RoomNumber = strings.TrimSpace(room.RoomNumber)
+	room.PropertyType = strings.TrimSpace(room.PropertyType)
+	if room.RoomNumber == "" {
+		return errors.New("房号不能为空")
+	}
+	if room.Area < 0 {
+		return errors.New("建筑面积不能小于0")
+	}
+	return nil
+}
+
+// checkRoomNumberUnique 检查单元内房号是否重复
+func checkRoomNumberUnique(tx *gorm.DB, unitID int64, roomNumber string, excludeID int64) error {
+	var count int64
+	if err := tx.Model(&models.Room{}).
+		Where("unit_id = ? AND room_number = ? AND is_del = 0 AND id <> ?", unitID, roomNumber, excludeID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("房号已存在")
+	}
+	return nil
+}
+
+// CreateRoom 在单元下新增房间（可以没有住户），未填写楼层时按房号推算
+func (s *HousingService) CreateRoom(unitID int64, room *models.Room) (*RoomInfo, error) {
+	if err := validateRoom(room); err != nil {
+		return nil, err
+	}
+	var roomID int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		unit, _, err := s.getUnit(tx, unitID)
+		if err != nil {
+			return err
+		}
+		if err := checkRoomNumberUnique(tx, unitID, room.RoomNumber, 0); err != nil {
+			return err
+		}
+		saved, err := ensureRoom(tx, unit, room.RoomNumber)
+		if err != nil {
+			return err
+		}
+		if room.Floor == 0 {
+			room.Floor = saved.Floor
+		}
+		roomID = saved.ID
+		return tx.Model(saved).Updates(map[string]interface{}{
+			"floor":         room.Floor,
+			"area":          room.Area,
+			"property_type": room.PropertyType,
+			"remark":        room.Remark,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRoom(roomID)
+}
+
+// UpdateRoom 更新房间信息；修改房号时同步更新住户的房号
+func (s *HousingService) UpdateRoom(id int64, room *models.Room) (*RoomInfo, error) {
+	if err := validateRoom(room); err != nil {
+		return nil, err
+	}
+	existing, err := s.GetRoom(id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if room.RoomNumber != existing.RoomNumber {
+			if err := checkRoomNumberUnique(tx, existing.UnitID, room.RoomNumber, id); err != nil {
+				return err
+			}
+			// 同单元已删除的同号房间改名，避免与唯一索引冲突
+			if err := tx.Model(&models.Room{}).
+				Where("unit_id = ? AND room_number = ? AND is_del = 1", existing.UnitID, room.RoomNumber).
+				Update("room_number", gorm.Expr("CONCAT(room_number, '#', id)")).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Person{}).Where("room_id = ?", id).
+				Update("room_number", room.RoomNumber).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Room{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"room_number":   room.RoomNumber,
+			"floor":         room.Floor,
+			"area":          room.Area,
+			"property_type": room.PropertyType,
+			"remark":        room.Remark,
+		}).Error; err != nil {
+			return errors.New("更新房间失败: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRoom(id)
+}
+
+// DeleteRoom 删除房间（软删除，房间没有住户时才可删除）
+func (s *HousingService) DeleteRoom(id int64) error {
+	room, err := s.GetRoom(id)
+	if err != nil {
+		return err
+	}
+	if room.PersonNum > 0 {
+		return errors.New("房间还有住户，不能删除")
+	}
+	return s.db.Model(&models.Room{}).Where("id = ?", id).Update("is_del", 1).Error
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RollbackImportBatch 回滚已完成的导入批次：删除新增的人员、恢复更新前的字段、恢复被删除的人员
+// 如果之后的导入批次修改过相同人员，需要先回滚之后的批次；
+// 导入后人员信息又被修改过（与导入写入的值不一致）时拒绝回滚，避免覆盖之后的修改；
+// 批次中有人员属于当前用户无权访问的楼栋时同样拒绝回滚
+// 参数:
+//   - batchID: 导入批次ID
+//
+// 返回值:
+//   - *models.ImportBatch: 回滚后的批次信息
+//   - error: 错误信息
+func (s *UpdateExcelDataService) RollbackImportBatch(batchID int64) (*models.ImportBatch, error) {
+	var batch models.ImportBatch
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&batch, batchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("导入批次不存在")
+			}
+			return err
+		}
+		if batch.Status != models.ImportBatchCompleted && batch.Status != models.ImportBatchPartial {
+			return fmt.Errorf("导入批次状态为 %s，只能回滚已完成或部分完成的批次", batch.Status)
+		}
+
+		// 检查之后的已完成（含部分完成）批次是否修改过相同人员
+		var laterCount int64
+		if err := tx.Model(&models.ImportBatchChange{}).
+			Joins("JOIN import_batch ON import_batch.id = import_batch_change.batch_id").
+			Where("import_batch.id > ? AND import_batch.status IN ?", batch.ID,
+				[]string{models.ImportBatchCompleted, models.ImportBatchPartial}).
+			Where("import_batch_change.person_id IN (?)",
+				tx.Model(&models.ImportBatchChange{}).Select("person_id").Where("batch_id = ?", batch.ID)).
+			Count(&laterCount).Error; err != nil {
+			return err
+		}
+		if laterCount > 0 {
+			return errors.New("之后的导入批次修改过相同人员，请先回滚之后的批次")
+		}
+
+		var changes []models.ImportBatchChange
+		if err := tx.Where("batch_id = ?", batch.ID).Order("id DESC").Find(&changes).Error; err != nil {
+			return err
+		}
+		if err := checkRollbackAccess(tx, s.access, changes); err != nil {
+			return err
+		}
+		if err := checkRollbackConflicts(tx, changes); err != nil {
+			return err
+		}
+
+		resolver := newRoomResolver(tx)
+		for _, change := range changes {
+			switch change.Action {
+			case models.ImportChangeInsert:
+				if err := tx.Model(&models.Person{}).Where("id = ?", change.PersonID).Update("is_del", 1).Error; err != nil {
+					return err
+				}
+				// 导入后登记的电动车随人员一起软删除
+				if _, err := removePersonsElectricBicycles(tx, []int64{change.PersonID}); err != nil {
+					return err
+				}
+			case models.ImportChangeRemove:
+				if err := tx.Model(&models.Person{}).Where("id = ?", change.PersonID).Update("is_del", 0).Error; err != nil {
+					return err
+				}
+				// 恢复随人员一起软删除的电动车
+				bicycleIDs, err := changeBicycleIDs(change.OldValues)
+				if err != nil {
+					return fmt.Errorf("解析人员 %d 的原始数据失败: %v", change.PersonID, err)
+				}
+				if err := setBicyclesDeleted(tx, change.PersonID, bicycleIDs, 0); err != nil {
+					return err
+				}
+				// 恢复的人员所在房间可能已删除或尚未关联
+				if err := syncPersonRoom(tx, resolver, change.PersonID); err != nil {
+					return err
+				}
+			case models.ImportChangeUpdate:
+				// 删除导入时登记的电动车
+				bicycleIDs, err := changeBicycleIDs(change.NewValues)
+				if err != nil {
+					return fmt.Errorf("解析人员 %d 的导入数据失败: %v", change.PersonID, err)
+				}
+				if err := setBicyclesDeleted(tx, change.PersonID, bicycleIDs, 1); err != nil {
+					return err
+				}
+				var oldValues map[string]interface{}
+				if err := json.Unmarshal([]byte(change.OldValues), &oldValues); err != nil {
+					return fmt.Errorf("解析人员 %d 的原始数据失败: %v", change.PersonID, err)
+				}
+				if len(oldValues) == 0 {
+					continue
+				}
+				if err := models.EncryptPersonValues(oldValues); err != nil {
+					return err
+				}
+				if err := tx.Model(&models.Person{}).Where("id = ?", change.PersonID).Updates(oldValues).Error; err != nil {
+					return err
+				}
+				if hasAddressChange(oldValues) {
+					if err := syncPersonRoom(tx, resolver, change.PersonID); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		now := time.Now()
+		batch.Status = models.ImportBatchRolledBack
+		batch.RolledBackAt = &now
+		return tx.Model(&batch).Updates(map[string]interface{}{
+			"status":         batch.Status,
+			"rolled_back_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// checkRollbackAccess 检查批次涉及的楼栋（人员当前所在楼栋及回滚后恢复的楼栋）是否都有权访问
+func checkRollbackAccess(tx *gorm.DB, access *UserAccess, changes []models.ImportBatchChange) error {
+	if access == nil || access.AllBuildings || len(changes) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.PersonID)
+	}
+	var buildings []string
+	if err := tx.Model(&models.Person{}).Where("id IN ?", ids).Distinct().Pluck("building_number", &buildings).Error; err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Action != models.ImportChangeUpdate {
+			continue
+		}
+		var oldValues map[string]interface{}
+		if err := json.Unmarshal([]byte(change.OldValues), &oldValues); err != nil {
+			return fmt.Errorf("解析人员 %d 的原始数据失败: %v", change.PersonID, err)
+		}
+		if building, ok := oldValues["building_number"].(string); ok {
+			buildings = append(buildings, building)
+		}
+	}
+	for _, building := range buildings {
+		if !access.CanAccessBuilding(building) {
+			return fmt.Errorf("导入批次包含楼栋 %s 的人员，%v，不能回滚", building, errNoBuildingAccess)
+		}
+	}
+	return nil
+}
+
+// maxRollbackConflicts 回滚冲突提示中最多列出的人数
+const maxRollbackConflicts = 10
+
+// checkRollbackConflicts 检查批次中的人员在导入后是否又被修改过，有修改时返回错误并列出相关人员
+func checkRollbackConflicts(tx *gorm.DB, changes []models.ImportBatchChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.PersonID)
+	}
+	var persons []models.Person
+	if err := tx.Where("id IN ?", ids).Find(&persons).Error; err != nil {
+		return err
+	}
+	current := make(map[int64]*models.Person, len(persons))
+	for i := range persons {
+		current[persons[i].ID] = &persons[i]
+	}
+
+	var conflicts []string
+	for _, change := range changes {
+		person, ok := current[change.PersonID]
+		if !ok {
+			continue
+		}
+		var reason string
+		switch change.Action {
+		case models.ImportChangeRemove:
+			if person.IsDel == 0 {
+				reason = "已被恢复"
+			}
+		case models.ImportChangeInsert, models.ImportChangeUpdate:
+			if change.Action == models.ImportChangeInsert && person.IsDel != 0 {
+				continue
+			}
+			columns, err := modifiedColumns(person, change.NewValues)
+			if err != nil {
+				return fmt.Errorf("解析人员 %d 的导入数据失败: %v", change.PersonID, err)
+			}
+			if len(columns) > 0 {
+				headers := make([]string, 0, len(columns))
+				for _, column := range columns {
+					headers = append(headers, models.GetExportFieldHeader(column))
+				}
+				reason = "已修改" + strings.Join(headers, "、")
+			}
+		}
+		if reason != "" {
+			conflicts = append(conflicts, fmt.Sprintf("%s（ID %d）%s", person.Name, person.ID, reason))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	message := strings.Join(conflicts, "；")
+	if len(conflicts) > maxRollbackConflicts {
+		message = strings.Join(conflicts[:maxRollbackConflicts], "；") + fmt.Sprintf(" 等 %d 人", len(conflicts))
+	}
+	return fmt.Errorf("以下人员在导入后已被修改，不能回滚：%s", message)
+}
+
+// modifiedColumns 比较人员当前值与导入写入的值，返回不一致的字段
+// 早期批次没有记录写入的值，无法检查时返回空
+func modifiedColumns(person *models.Person, newValues string) ([]string, error) {
+	if newValues == "" {
+		return nil, nil
+	}
+	var written map[string]interface{}
+	if err := json.Unmarshal([]byte(newValues), &written); err != nil {
+		return nil, err
+	}
+	currentValues := personImportValues(person)
+	var columns []string
+	for column, value := range written {
+		// 年龄随出生日期自动计算，不作为是否修改过的依据；盲索引等辅助列不在比较范围内
+		currentValue, ok := currentValues[column]
+		if !ok || column == "age" {
+			continue
+		}
+		// 敏感字段写入的是密文，解密后比较
+		if str, ok := value.(string); ok {
+			plaintext, err := fieldcrypt.Decrypt(str)
+			if err != nil {
+				return nil, err
+			}
+			value = plaintext
+		}
+		// JSON 解码后数字为 float64，统一按文本比较
+		if fmt.Sprint(currentValue) != fmt.Sprint(value) {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"PLMS/internal/models"
+)
+
+func TestModifiedColumns(t *testing.T) {
+	person := &models.Person{
+		BuildingNumber: "1",
+		UnitNumber:     1,
+		RoomNumber:     "101",
+		Name:           "张三",
+		Telephone:      "13812345678",
+		Age:            71,
+	}
+	tests := []struct {
+		name      string
+		newValues string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "早期批次未记录写入的值", newValues: ""},
+		{name: "未被修改", newValues: `{"name":"张三","telephone":"13812345678","unit_number":1}`},
+		{name: "年龄不作为修改依据", newValues: `{"name":"张三","age":70}`},
+		{name: "非导入字段不比较", newValues: `{"name":"张三","id_card_bidx":"abc"}`},
+		{name: "字段已被修改", newValues: `{"name":"张三","telephone":"13987654321","room_number":"102"}`, want: []string{"room_number", "telephone"}},
+		{name: "数据格式错误", newValues: `{"name":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := modifiedColumns(person, tt.newValues)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("modifiedColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("modifiedColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
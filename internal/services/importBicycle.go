@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// bicycleIDsKey 导入批次变更记录中保存电动车ID的键：
+// 软删除人员时记录在 OldValues 中（回滚时恢复），导入登记电动车时记录在 NewValues 中（回滚时删除）
+const bicycleIDsKey = "electric_bicycle_ids"
+
+// unknownBicycleModel 表中没有品牌型号时登记的车型
+const unknownBicycleModel = "未登记"
+
+// bicycleImport 导入时需要登记的电动车
+type bicycleImport struct {
+	Row         int    // Excel行号
+	PersonID    int64  // 已有人员ID，新增人员时为0
+	Insert      int    // 新增人员在 upsertPlan.Inserts 中的下标（PersonID 为0时有效）
+	PlateNumber string // 车牌号
+	BrandModel  string // 品牌型号
+}
+
+// planBicycles 筛选需要登记的电动车：车牌号已登记在本人名下的跳过，
+// 已登记在其他人员名下、同一工作表中重复或格式错误的跳过并提示
+func planBicycles(db *gorm.DB, sheet string, plan *upsertPlan, candidates []bicycleImport) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+	plates := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		plates = append(plates, candidate.PlateNumber)
+	}
+	var registered []models.ElectricBicycle
+	if err := db.Select("id, person_id, plate_number").
+		Where("plate_number IN ? AND is_del = 0", plates).Find(&registered).Error; err != nil {
+		return err
+	}
+	owners := make(map[string]int64, len(registered))
+	for _, bicycle := range registered {
+		owners[bicycle.PlateNumber] = bicycle.PersonID
+	}
+
+	firstRows := make(map[string]int)
+	for _, candidate := range candidates {
+		plate := candidate.PlateNumber
+		if len(plate) > 20 {
+			plan.Issues = append(plan.Issues, newImportIssue(sheet, candidate.Row, "license_plate", plate, ImportIssueWarning,
+				"车牌号码长度不能超过20位，未登记电动车"))
+			continue
+		}
+		if row, ok := firstRows[plate]; ok {
+			plan.Issues = append(plan.Issues, newImportIssue(sheet, candidate.Row, "license_plate", plate, ImportIssueWarning,
+				fmt.Sprintf("车牌号与第 %d 行重复，未登记电动车", row)))
+			continue
+		}
+		firstRows[plate] = candidate.Row
+		if owner, ok := owners[plate]; ok {
+			if owner != candidate.PersonID {
+				plan.Issues = append(plan.Issues, newImportIssue(sheet, candidate.Row, "license_plate", plate, ImportIssueWarning,
+					"车牌号已登记在其他人员名下，未登记电动车"))
+			}
+			continue
+		}
+		plan.Bicycles = append(plan.Bicycles, candidate)
+	}
+	return nil
+}
+
+// createImportBicycles 登记导入的电动车并同步人员的电动车字段，返回各人员新登记的电动车ID
+// 需在新增人员保存后调用
+func createImportBicycles(db *gorm.DB, plan *upsertPlan) (map[int64][]int64, error) {
+	created := make(map[int64][]int64)
+	for _, item := range plan.Bicycles {
+		personID := item.PersonID
+		if personID == 0 {
+			personID = plan.Inserts[item.Insert].ID
+		}
+		model := item.BrandModel
+		if model == "" {
+			model = unknownBicycleModel
+		}
+		if runes := []rune(model); len(runes) > 50 {
+			model = string(runes[:50])
+		}
+		bicycle := models.ElectricBicycle{PersonID: personID, Model: model, PlateNumber: item.PlateNumber}
+		if err := db.Create(&bicycle).Error; err != nil {
+			return nil, err
+		}
+		created[personID] = append(created[personID], bicycle.ID)
+	}
+	for personID := range created {
+		if err := syncPersonElectricCar(db, personID); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// withBicycleIDs 在变更记录的字段值（JSON）中加入电动车ID
+func withBicycleIDs(values string, ids []int64) (string, error) {
+	decoded := make(map[string]interface{})
+	if values != "" {
+		if err := json.Unmarshal([]byte(values), &decoded); err != nil {
+			return "", err
+		}
+	}
+	decoded[bicycleIDsKey] = ids
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// changeBicycleIDs 解析变更记录的字段值（JSON）中保存的电动车ID
+func changeBicycleIDs(values string) ([]int64, error) {
+	if values == "" {
+		return nil, nil
+	}
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(values), &decoded); err != nil {
+		return nil, err
+	}
+	raw, ok := decoded[bicycleIDsKey]
+	if !ok {
+		return nil, nil
+	}
+	var ids []int64
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// setBicyclesDeleted 回滚导入时删除或恢复变更记录中的电动车，并同步人员的电动车字段
+func setBicyclesDeleted(tx *gorm.DB, personID int64, ids []int64, isDel int8) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.ElectricBicycle{}).Where("id IN ? AND person_id = ?", ids, personID).
+		Update("is_del", isDel).Error; err != nil {
+		return err
+	}
+	return syncPersonElectricCar(tx, personID)
+}
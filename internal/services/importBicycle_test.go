@@ -0,0 +1,39 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBicycleIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		ids    []int64
+		want   string
+	}{
+		{name: "空的字段值", values: "", ids: []int64{3, 5}, want: `{"electric_bicycle_ids":[3,5]}`},
+		{name: "保留已有字段", values: `{"telephone":"13812345678"}`, ids: []int64{7}, want: `{"electric_bicycle_ids":[7],"telephone":"13812345678"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withBicycleIDs(tt.values, tt.ids)
+			if err != nil || got != tt.want {
+				t.Fatalf("withBicycleIDs() = %q, %v, want %q", got, err, tt.want)
+			}
+			ids, err := changeBicycleIDs(got)
+			if err != nil || !slices.Equal(ids, tt.ids) {
+				t.Errorf("changeBicycleIDs(%q) = %v, %v, want %v", got, ids, err, tt.ids)
+			}
+		})
+	}
+
+	for _, values := range []string{"", "{}", `{"telephone":"13812345678"}`} {
+		if ids, err := changeBicycleIDs(values); err != nil || ids != nil {
+			t.Errorf("changeBicycleIDs(%q) = %v, %v, 应没有电动车ID", values, ids, err)
+		}
+	}
+	if _, err := changeBicycleIDs(`{"electric_bicycle_ids":"1"}`); err == nil {
+		t.Error("电动车ID格式错误应返回错误")
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 工作表处理状态
+const (
+	SheetProgressPending   = "pending"
+	SheetProgressRunning   = "running"
+	SheetProgressCompleted = "completed"
+	SheetProgressFailed    = "failed"
+)
+
+// importQueueSize 导入任务队列长度
+const importQueueSize = 20
+
+// SheetProgress 工作表处理进度
+type SheetProgress struct {
+	Sheet     string `json:"sheet"`
+	Status    string `json:"status"` // pending/running/completed/failed
+	Persons   int    `json:"persons"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Message   string `json:"message,omitempty"`
+}
+
+// ImportJobView 导入任务（含解析后的进度和结果）
+type ImportJobView struct {
+	models.ImportJob
+	Progress []SheetProgress `json:"progress"`
+	Result   *ImportResult   `json:"result"`
+}
+
+// importTask 导入任务：读取工作表的方法由提交方提供
+type importTask struct {
+	job     *models.ImportJob
+	opts    ImportOptions
+	load    func(result *ImportResult) ([]parsedSheet, error)
+	cleanup func()
+}
+
+var (
+	// importRunMu 保证同一时间只执行一个导入，避免并发导入相互覆盖
+	importRunMu sync.Mutex
+	// importQueue 后台导入任务队列
+	importQueue      = make(chan func(), importQueueSize)
+	importWorkerOnce sync.Once
+)
+
+// StartImportWorker 启动后台导入协程（只启动一次），并将上次服务退出时未完成的任务标记为失败
+// 服务启动时调用，确保重启前中断的任务不会一直停留在执行中
+func StartImportWorker(db *gorm.DB) {
+	importWorkerOnce.Do(func() {
+		now := time.Now()
+		if err := db.Model(&models.ImportJob{}).
+			Where("status IN ?", []string{models.ImportJobPending, models.ImportJobRunning}).
+			Updates(map[string]interface{}{
+				"status":      models.ImportJobFailed,
+				"error":       "服务重启，任务中断",
+				"finished_at": now,
+			}).Error; err != nil {
+			log.Printf("标记中断的导入任务失败: %v", err)
+		}
+		go func() {
+			for task := range importQueue {
+				task()
+			}
+		}()
+	})
+}
+
+// hashFile 计算文件的 SHA-256 及大小
+func hashFile(filePath string) (string, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// newImportJob 创建导入任务记录
+func (s *UpdateExcelDataService) newImportJob(opts ImportOptions, fileHash string, fileSize int64) (*models.ImportJob, error) {
+	job := &models.ImportJob{
+		FileName:   opts.FileName,
+		FileHash:   fileHash,
+		FileSize:   fileSize,
+		Uploader:   opts.Operator,
+		UploaderID: opts.OperatorID,
+		Profile:    opts.Profile,
+		Status:     models.ImportJobPending,
+	}
+	if job.Profile == "" {
+		job.Profile = DefaultImportProfileName
+	}
+	if opts.RemoveMissing {
+		job.RemoveMissing = 1
+	}
+	if opts.Transactional {
+		job.Transactional = 1
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, errors.New("创建导入任务失败: " + err.Error())
+	}
+	return job, nil
+}
+
+// saveJobProgress 保存任务各工作表进度，保存失败只记录日志，不影响导入
+func (s *UpdateExcelDataService) saveJobProgress(job *models.ImportJob, progress []SheetProgress) {
+	encoded, _ := json.Marshal(progress)
+	job.ProgressJSON = string(encoded)
+	if err := s.db.Model(job).Update("progress", job.ProgressJSON).Error; err != nil {
+		log.Printf("保存导入任务 %d 进度失败: %v", job.ID, err)
+	}
+}
+
+// runImportTask 执行导入任务并记录进度和结果
+func (s *UpdateExcelDataService) runImportTask(task *importTask) (*ImportResult, error) {
+	importRunMu.Lock()
+	defer importRunMu.Unlock()
+	if task.cleanup != nil {
+		defer task.cleanup()
+	}
+
+	job := task.job
+	started := time.Now()
+	job.Status = models.ImportJobRunning
+	job.StartedAt = &started
+	result := newImportResult()
+	if err := s.db.Model(job).Updates(map[string]interface{}{
+		"status":     job.Status,
+		"started_at": started,
+	}).Error; err != nil {
+		return result, errors.New("更新导入任务状态失败: " + err.Error())
+	}
+
+	finish := func(err error) {
+		finished := time.Now()
+		job.FinishedAt = &finished
+		job.BatchID = result.BatchID
+		job.Status = models.ImportJobCompleted
+		if err != nil {
+			job.Status = models.ImportJobFailed
+			job.Error = err.Error()
+		}
+		encoded, _ := json.Marshal(result)
+		job.ResultJSON = string(encoded)
+		if dbErr := s.db.Model(job).Updates(map[string]interface{}{
+			"status":      job.Status,
+			"error":       job.Error,
+			"batch_id":    job.BatchID,
+			"result":      job.ResultJSON,
+			"finished_at": finished,
+		}).Error; dbErr != nil {
+			log.Printf("保存导入任务 %d 结果失败: %v", job.ID, dbErr)
+		}
+	}
+
+	sheets, err := task.load(result)
+	if err != nil {
+		finish(err)
+		return result, err
+	}
+
+	progress := make([]SheetProgress, 0, len(sheets))
+	index := make(map[string]int, len(sheets))
+	for _, sheet := range sheets {
+		index[sheet.Name] = len(progress)
+		progress = append(progress, SheetProgress{Sheet: sheet.Name, Status: SheetProgressPending})
+	}
+	s.saveJobProgress(job, progress)
+
+	err = s.applyImport(sheets, task.opts, result, func(p SheetProgress) {
+		if i, ok := index[p.Sheet]; ok {
+			progress[i] = p
+			s.saveJobProgress(job, progress)
+		}
+	})
+	finish(err)
+	return result, err
+}
+
+// SubmitImportJob 提交Excel导入任务
+// async 为 true 时任务放入后台队列并立即返回，否则同步执行完成后返回
+// 任务结束后删除 filePath 对应的临时文件
+// 参数:
+//   - filePath: Excel文件路径
+//   - opts: 导入选项
+//   - async: 是否异步执行
+//
+// 返回值:
+//   - *ImportJobView: 导入任务
+//   - error: 错误信息
+func (s *UpdateExcelDataService) SubmitImportJob(filePath string, opts ImportOptions, async bool) (*ImportJobView, error) {
+	profile, err := s.GetImportProfile(opts.Profile)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	opts.Profile = profile.Name
+
+	fileHash, fileSize, err := hashFile(filePath)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	job, err := s.newImportJob(opts, fileHash, fileSize)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	task := &importTask{
+		job:  job,
+		opts: opts,
+		load: func(result *ImportResult) ([]parsedSheet, error) {
+			return parseWorkbook(filePath, profile, result)
+		},
+		cleanup: func() { os.Remove(filePath) },
+	}
+
+	if !async {
+		if _, err := s.runImportTask(task); err != nil && job.Status != models.ImportJobFailed {
+			// 任务状态未能记录时直接返回错误，否则失败原因记录在任务中
+			return nil, err
+		}
+		return s.GetImportJob(job.ID, 0)
+	}
+
+	StartImportWorker(s.db)
+	select {
+	case importQueue <- func() { s.runImportTask(task) }:
+	default:
+		os.Remove(filePath)
+		if err := s.db.Model(job).Updates(map[string]interface{}{
+			"status": models.ImportJobFailed,
+			"error":  "导入任务队列已满",
+		}).Error; err != nil {
+			log.Printf("保存导入任务 %d 状态失败: %v", job.ID, err)
+		}
+		return nil, errors.New("导入任务队列已满，请稍后再试")
+	}
+	return s.GetImportJob(job.ID, 0)
+}
+
+// toImportJobView 解析任务中的进度和结果
+func toImportJobView(job models.ImportJob) *ImportJobView {
+	view := &ImportJobView{ImportJob: job, Progress: []SheetProgress{}}
+	if job.ProgressJSON != "" {
+		_ = json.Unmarshal([]byte(job.ProgressJSON), &view.Progress)
+	}
+	if job.ResultJSON != "" {
+		var result ImportResult
+		if err := json.Unmarshal([]byte(job.ResultJSON), &result); err == nil {
+			view.Result = &result
+		}
+	}
+	return view
+}
+
+// GetImportJob 获取导入任务状态
+// uploaderID 不为 0 时只能查看该用户提交的任务
+func (s *UpdateExcelDataService) GetImportJob(jobID, uploaderID int64) (*ImportJobView, error) {
+	var job models.ImportJob
+	query := s.db.Where("id = ?", jobID)
+	if uploaderID != 0 {
+		query = query.Where("uploader_id = ?", uploaderID)
+	}
+	if err := query.First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("导入任务不存在")
+		}
+		return nil, err
+	}
+	return toImportJobView(job), nil
+}
+
+// GetImportJobList 获取导入历史（分页，按创建时间倒序）
+// uploaderID 不为 0 时只返回该用户提交的任务
+func (s *UpdateExcelDataService) GetImportJobList(page, pageSize int, uploaderID int64) ([]*ImportJobView, int64, error) {
+	var jobs []models.ImportJob
+	var total int64
+
+	filtered := func() *gorm.DB {
+		query := s.db.Model(&models.ImportJob{})
+		if uploaderID != 0 {
+			query = query.Where("uploader_id = ?", uploaderID)
+		}
+		return query
+	}
+
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := filtered().Order("id DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	views := make([]*ImportJobView, 0, len(jobs))
+	for _, job := range jobs {
+		views = append(views, toImportJobView(job))
+	}
+	return views, total, nil
+}
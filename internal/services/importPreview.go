@@ -0,0 +1,388 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"PLMS/internal/idcard"
+	"PLMS/internal/models"
+)
+
+// 问题级别
+const (
+	ImportIssueError   = "error"   // 该行无法导入
+	ImportIssueWarning = "warning" // 该行可以导入，但数据可能有误
+)
+
+// previewTTL 预览结果的有效期，超时后需重新上传
+const previewTTL = 30 * time.Minute
+
+// ImportIssue 导入数据校验发现的问题
+type ImportIssue struct {
+	Sheet   string `json:"sheet"`   // 工作表
+	Row     int    `json:"row"`     // Excel行号
+	Field   string `json:"field"`   // 字段
+	Value   string `json:"value"`   // 单元格的值
+	Level   string `json:"level"`   // 级别：error/warning
+	Message string `json:"message"` // 问题说明
+}
+
+func newImportIssue(sheet string, row int, field, value, level, message string) ImportIssue {
+	return ImportIssue{Sheet: sheet, Row: row, Field: field, Value: value, Level: level, Message: message}
+}
+
+// FieldChange 字段变化
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// PersonDiff 导入数据与现有数据的差异
+type PersonDiff struct {
+	Row            int                    `json:"row"`      // Excel行号，删除时为0
+	Action         string                 `json:"action"`   // insert/update/remove
+	PersonID       int64                  `json:"personId"` // 已有人员ID，新增时为0
+	Name           string                 `json:"name"`
+	BuildingNumber string                 `json:"buildingNumber"`
+	UnitNumber     int                    `json:"unitNumber"`
+	RoomNumber     string                 `json:"roomNumber"`
+	Changes        map[string]FieldChange `json:"changes,omitempty"` // 更新的字段
+}
+
+// SheetPreview 单个工作表的预览结果
+type SheetPreview struct {
+	Sheet        string        `json:"sheet"`
+	TotalPersons int           `json:"totalPersons"`
+	Inserted     int           `json:"inserted"`
+	Updated      int           `json:"updated"`
+	Unchanged    int           `json:"unchanged"`
+	Bicycles     int           `json:"bicycles"`        // 新登记的电动车数
+	Error        string        `json:"error,omitempty"` // 读取失败原因
+	Issues       []ImportIssue `json:"issues"`
+	Diffs        []PersonDiff  `json:"diffs"`
+}
+
+// ImportPreview 导入预览结果
+type ImportPreview struct {
+	ImportResult
+	Token        string         `json:"token"`     // 确认导入时使用
+	ExpiresAt    time.Time      `json:"expiresAt"` // 过期时间
+	Profile      string         `json:"profile"`
+	ErrorCount   int            `json:"errorCount"`
+	WarningCount int            `json:"warningCount"`
+	Sheets       []SheetPreview `json:"sheets"`
+	Removals     []PersonDiff   `json:"removals"` // 将被软删除的人员（removeMissing 时）
+}
+
+// pendingImport 已预览、等待确认的导入
+type pendingImport struct {
+	sheets    []parsedSheet
+	opts      ImportOptions
+	fileHash  string
+	fileSize  int64
+	expiresAt time.Time
+}
+
+// pendingImports 预览结果缓存（token -> 待确认导入）
+var pendingImports = struct {
+	sync.Mutex
+	m map[string]*pendingImport
+}{m: make(map[string]*pendingImport)}
+
+// newPreviewToken 生成预览token
+func newPreviewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// storePendingImport 保存待确认的导入，同时清理已过期的记录
+func storePendingImport(token string, pending *pendingImport) {
+	pendingImports.Lock()
+	defer pendingImports.Unlock()
+	now := time.Now()
+	for t, p := range pendingImports.m {
+		if now.After(p.expiresAt) {
+			delete(pendingImports.m, t)
+		}
+	}
+	pendingImports.m[token] = pending
+}
+
+// takePendingImport 取出待确认的导入（取出后失效），非预览的用户不能取出
+func takePendingImport(token string, operatorID int64) (*pendingImport, error) {
+	pendingImports.Lock()
+	defer pendingImports.Unlock()
+	pending, ok := pendingImports.m[token]
+	if !ok {
+		return nil, errors.New("预览结果不存在或已确认导入")
+	}
+	if pending.opts.OperatorID != operatorID {
+		return nil, errors.New("只能由预览导入的用户确认导入")
+	}
+	delete(pendingImports.m, token)
+	if time.Now().After(pending.expiresAt) {
+		return nil, errors.New("预览结果已过期，请重新上传")
+	}
+	return pending, nil
+}
+
+var (
+	mobileRegexp   = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	landlineRegexp = regexp.MustCompile(`^(0\d{2,3}-?)?\d{7,8}(-\d{1,6})?$`)
+	phoneSplitter  = regexp.MustCompile(`[/、,，;；\s]+`)
+)
+
+// validatePhone 校验电话号码（支持多个号码，以 / 、 , 等分隔）
+func validatePhone(phone string) bool {
+	for _, p := range phoneSplitter.Split(strings.TrimSpace(phone), -1) {
+		if p == "" {
+			continue
+		}
+		if !mobileRegexp.MatchString(p) && !landlineRegexp.MatchString(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyImportIDCard 校验导入行的身份证号，有效时按身份证号设置出生日期、性别和年龄，与表中填写的值不符时给出提示
+// 身份证号为空或无效但表中有出生日期时，按出生日期计算年龄
+func applyImportIDCard(sheet string, row int, person *models.Person, now time.Time) []ImportIssue {
+	var issues []ImportIssue
+	warn := func(field, value, message string) {
+		issues = append(issues, newImportIssue(sheet, row, field, value, ImportIssueWarning, message))
+	}
+
+	if person.IDCard != "" {
+		info, err := idcard.Parse(person.IDCard)
+		if err != nil {
+			warn("id_card", person.IDCard, err.Error())
+		} else {
+			if person.Gender != 0 && person.Gender != info.Gender {
+				warn("gender", strconv.Itoa(person.Gender), "性别与身份证号不符，已按身份证号更正")
+			}
+			if expected := info.Age(now); person.Age != 0 && (person.Age < expected-1 || person.Age > expected+1) {
+				warn("age", strconv.Itoa(person.Age), fmt.Sprintf("年龄与身份证号不符，已按身份证号更正为 %d 岁", expected))
+			}
+			if person.BirthDate != nil && !person.BirthDate.Equal(info.BirthDate) {
+				warn("birth_date", person.BirthDate.Format("2006-01-02"), "出生日期与身份证号不符，已按身份证号更正")
+			}
+			person.ApplyIDCard(now)
+			return issues
+		}
+	}
+	if person.BirthDate != nil {
+		person.Age = idcard.AgeAt(*person.BirthDate, now)
+	}
+	return issues
+}
+
+// validateImportPerson 校验单行导入数据，返回发现的问题
+func validateImportPerson(sheet string, row int, person *models.Person) []ImportIssue {
+	var issues []ImportIssue
+	warn := func(field, value, message string) {
+		issues = append(issues, newImportIssue(sheet, row, field, value, ImportIssueWarning, message))
+	}
+
+	if person.Name == "" {
+		warn("name", "", "姓名为空")
+	}
+
+	// 身份证号有效时年龄已按身份证号计算，只校验手工填写的年龄
+	if !idcard.Valid(person.IDCard) && (person.Age < 0 || person.Age > 120) {
+		warn("age", strconv.Itoa(person.Age), "年龄超出范围（0-120）")
+	}
+
+	if person.Telephone != "" && !validatePhone(person.Telephone) {
+		warn("telephone", person.Telephone, "联系方式格式错误")
+	}
+	if person.ElderContactPhone != "" && !validatePhone(person.ElderContactPhone) {
+		warn("elder_contact_phone", person.ElderContactPhone, "紧急联系电话格式错误")
+	}
+	return issues
+}
+
+// newPersonDiff 生成人员差异记录
+func newPersonDiff(row int, action string, person *models.Person) PersonDiff {
+	return PersonDiff{
+		Row:            row,
+		Action:         action,
+		PersonID:       person.ID,
+		Name:           person.Name,
+		BuildingNumber: person.BuildingNumber,
+		UnitNumber:     person.UnitNumber,
+		RoomNumber:     person.RoomNumber,
+	}
+}
+
+// PreviewImport 预览导入（不写入数据库）：读取工作簿、逐行校验并与现有数据比对
+// 返回的 token 可在有效期内通过 CommitImport 确认导入
+// 参数:
+//   - filePath: Excel文件路径
+//   - opts: 导入选项
+//
+// 返回值:
+//   - *ImportPreview: 预览结果
+//   - error: 错误信息
+func (s *UpdateExcelDataService) PreviewImport(filePath string, opts ImportOptions) (*ImportPreview, error) {
+	profile, err := s.GetImportProfile(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	opts.Profile = profile.Name
+	fileHash, fileSize, err := hashFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &ImportPreview{
+		ImportResult: *newImportResult(),
+		Profile:      profile.Name,
+		Sheets:       []SheetPreview{},
+		Removals:     []PersonDiff{},
+	}
+	sheets, err := parseWorkbook(filePath, profile, &preview.ImportResult)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	matchedIDs := make(map[int64]struct{})
+	importedBuildings := make(map[string]struct{})
+	hasFailure := false
+
+	for _, sheet := range sheets {
+		sp := SheetPreview{Sheet: sheet.Name, Issues: []ImportIssue{}, Diffs: []PersonDiff{}}
+		if sheet.Err != nil {
+			hasFailure = true
+			sp.Error = sheet.Err.Error()
+			preview.Sheets = append(preview.Sheets, sp)
+			continue
+		}
+
+		data := sheet.Data
+		sp.TotalPersons = len(data.Persons)
+		sp.Issues = append(sp.Issues, data.Issues...)
+		for i := range data.Persons {
+			sp.Issues = append(sp.Issues, validateImportPerson(sheet.Name, data.Rows[i], &data.Persons[i])...)
+		}
+
+		plan, err := planUpsert(s.db, s.access, sheet.Name, data, matchedIDs)
+		if err != nil {
+			return nil, err
+		}
+		sp.Issues = append(sp.Issues, plan.Issues...)
+		for i := range plan.Inserts {
+			sp.Diffs = append(sp.Diffs, newPersonDiff(plan.InsertRows[i], "insert", &plan.Inserts[i]))
+		}
+		for _, update := range plan.Updates {
+			diff := newPersonDiff(update.Row, "update", update.Old)
+			diff.Changes = make(map[string]FieldChange, len(update.Changes))
+			oldValues := personImportValues(update.Old)
+			for column, value := range update.Changes {
+				diff.Changes[column] = FieldChange{Old: oldValues[column], New: value}
+			}
+			sp.Diffs = append(sp.Diffs, diff)
+		}
+		sp.Inserted = len(plan.Inserts)
+		sp.Updated = len(plan.Updates)
+		sp.Unchanged = plan.Unchanged
+		sp.Bicycles = len(plan.Bicycles)
+		for _, person := range data.Persons {
+			if s.access.CanAccessBuilding(person.BuildingNumber) {
+				importedBuildings[person.BuildingNumber] = struct{}{}
+			}
+		}
+
+		for _, issue := range sp.Issues {
+			if issue.Level == ImportIssueError {
+				preview.ErrorCount++
+			} else {
+				preview.WarningCount++
+			}
+		}
+		preview.Inserted += sp.Inserted
+		preview.Updated += sp.Updated
+		preview.Unchanged += sp.Unchanged
+		preview.Sheets = append(preview.Sheets, sp)
+	}
+
+	// 预览将被软删除的人员
+	if opts.RemoveMissing && !hasFailure && len(importedBuildings) > 0 {
+		var missing []models.Person
+		if err := missingPersonsQuery(s.db, importedBuildings, matchedIDs).Find(&missing).Error; err != nil {
+			return nil, err
+		}
+		for i := range missing {
+			preview.Removals = append(preview.Removals, newPersonDiff(0, "remove", &missing[i]))
+		}
+		preview.Removed = len(missing)
+	}
+
+	token, err := newPreviewToken()
+	if err != nil {
+		return nil, err
+	}
+	preview.Token = token
+	preview.ExpiresAt = now.Add(previewTTL)
+	storePendingImport(token, &pendingImport{
+		sheets:    sheets,
+		opts:      opts,
+		fileHash:  fileHash,
+		fileSize:  fileSize,
+		expiresAt: preview.ExpiresAt,
+	})
+
+	preview.Details = append(preview.Details, fmt.Sprintf("预览完成，共 %d 个工作表，%d 条人员数据（新增 %d，更新 %d，未变化 %d，删除 %d），错误 %d 条，警告 %d 条",
+		preview.TotalSheets, preview.TotalPersons, preview.Inserted, preview.Updated, preview.Unchanged, preview.Removed,
+		preview.ErrorCount, preview.WarningCount))
+	return preview, nil
+}
+
+// CommitImport 确认导入已预览的数据（同步执行，并记录导入任务）
+// 只能由预览的用户确认，楼栋范围按确认时的权限重新校验
+// 参数:
+//   - token: PreviewImport 返回的 token
+//   - operatorID: 确认导入的操作人用户ID
+//   - operator: 确认导入的操作人
+//
+// 返回值:
+//   - *ImportResult: 导入结果
+//   - error: 错误信息
+func (s *UpdateExcelDataService) CommitImport(token string, operatorID int64, operator string) (*ImportResult, error) {
+	pending, err := takePendingImport(token, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if operator != "" {
+		pending.opts.Operator = operator
+	}
+
+	job, err := s.newImportJob(pending.opts, pending.fileHash, pending.fileSize)
+	if err != nil {
+		return nil, err
+	}
+	return s.runImportTask(&importTask{
+		job:  job,
+		opts: pending.opts,
+		load: func(result *ImportResult) ([]parsedSheet, error) {
+			for _, sheet := range pending.sheets {
+				if sheet.Err == nil {
+					result.TotalSheets++
+					result.TotalPersons += len(sheet.Data.Persons)
+				}
+			}
+			return pending.sheets, nil
+		},
+	})
+}
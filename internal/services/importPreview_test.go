@@ -0,0 +1,24 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakePendingImport(t *testing.T) {
+	storePendingImport("token-1", &pendingImport{opts: ImportOptions{OperatorID: 1}, expiresAt: time.Now().Add(previewTTL)})
+	storePendingImport("token-2", &pendingImport{opts: ImportOptions{OperatorID: 1}, expiresAt: time.Now().Add(-time.Minute)})
+
+	if _, err := takePendingImport("token-1", 2); err == nil {
+		t.Error("其他用户不能确认导入")
+	}
+	if _, err := takePendingImport("token-1", 1); err != nil {
+		t.Errorf("预览的用户确认导入返回错误: %v", err)
+	}
+	if _, err := takePendingImport("token-1", 1); err == nil {
+		t.Error("已确认的预览不能重复确认")
+	}
+	if _, err := takePendingImport("token-2", 1); err == nil {
+		t.Error("过期的预览不能确认")
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 地址格式
+const (
+	AddressFormatBuildingRoom     = "building-room"      // 楼号-房号（单元默认为1）
+	AddressFormatBuildingUnitRoom = "building-unit-room" // 楼号-单元-房号
+	AddressFormatRoom             = "room"               // 只有房号，楼号取 buildingNumber 或工作表名称中的数字
+	AddressFormatChinese          = "chinese"            // X楼Y单元Z
+)
+
+// DefaultImportProfileName 内置导入方案名称
+const DefaultImportProfileName = "default"
+
+// SheetLayout 工作表布局，未设置的字段使用所属方案的默认值
+// 字段所在列默认按表头文字识别，Columns 中指定的字段以配置为准
+type SheetLayout struct {
+	Name           string              `json:"name"`           // 工作表名称
+	HeaderRow      int                 `json:"headerRow"`      // 表头所在行（从1开始）
+	DataStartRow   int                 `json:"dataStartRow"`   // 数据起始行（从1开始），为0时取表头下一行
+	AddressColumn  string              `json:"addressColumn"`  // 地址所在列，如 "B"
+	AddressFormat  string              `json:"addressFormat"`  // 地址格式
+	BuildingNumber string              `json:"buildingNumber"` // 地址格式为 room 时使用的楼号
+	Columns        map[string]string   `json:"columns"`        // 指定字段所在列，如 {"name": "C"}
+	HeaderAliases  map[string][]string `json:"headerAliases"`  // 额外的表头别名，如 {"telephone": ["户主电话"]}
+}
+
+// ImportProfile 导入方案，描述需要读取的工作表及其布局
+//
+// 方案文件为 JSON 格式，存放在 IMPORT_PROFILE_DIR 目录下，例如：
+//
+//	{
+//	  "name": "community-2025",
+//	  "headerRow": 8,
+//	  "addressColumn": "B",
+//	  "addressFormat": "building-room",
+//	  "columns": {"name": "C"},
+//	  "headerAliases": {"telephone": ["户主电话"]},
+//	  "sheets": [
+//	    {"name": "101楼"},
+//	    {"name": "110楼", "addressFormat": "room"}
+//	  ]
+//	}
+type ImportProfile struct {
+	Name        string        `json:"name"`        // 方案名称
+	Description string        `json:"description"` // 方案说明
+	SheetLayout               // 各工作表的默认布局
+	Sheets      []SheetLayout `json:"sheets"` // 需要处理的工作表
+}
+
+// SheetNames 返回方案中需要处理的工作表名称
+func (p *ImportProfile) SheetNames() []string {
+	names := make([]string, 0, len(p.Sheets))
+	for _, sheet := range p.Sheets {
+		names = append(names, sheet.Name)
+	}
+	return names
+}
+
+// Layout 返回指定工作表合并默认值后的布局，工作表不在方案中时返回 false
+func (p *ImportProfile) Layout(sheet string) (SheetLayout, bool) {
+	for _, s := range p.Sheets {
+		if s.Name != sheet {
+			continue
+		}
+		layout := s
+		if layout.HeaderRow == 0 {
+			layout.HeaderRow = p.HeaderRow
+		}
+		if layout.DataStartRow == 0 {
+			layout.DataStartRow = p.DataStartRow
+		}
+		if layout.DataStartRow == 0 {
+			layout.DataStartRow = layout.HeaderRow + 1
+		}
+		if layout.AddressColumn == "" {
+			layout.AddressColumn = p.AddressColumn
+		}
+		if layout.AddressFormat == "" {
+			layout.AddressFormat = p.AddressFormat
+		}
+		if layout.BuildingNumber == "" {
+			layout.BuildingNumber = p.BuildingNumber
+		}
+		columns := make(map[string]string, len(p.Columns)+len(s.Columns))
+		for field, col := range p.Columns {
+			columns[field] = col
+		}
+		for field, col := range s.Columns {
+			columns[field] = col
+		}
+		layout.Columns = columns
+		aliases := make(map[string][]string, len(p.HeaderAliases)+len(s.HeaderAliases))
+		for field, names := range p.HeaderAliases {
+			aliases[field] = append(aliases[field], names...)
+		}
+		for field, names := range s.HeaderAliases {
+			aliases[field] = append(aliases[field], names...)
+		}
+		layout.HeaderAliases = aliases
+		return layout, true
+	}
+	return SheetLayout{}, false
+}
+
+// validate 校验方案配置
+func (p *ImportProfile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("导入方案名称不能为空")
+	}
+	if len(p.Sheets) == 0 {
+		return fmt.Errorf("导入方案 [%s] 未配置工作表", p.Name)
+	}
+	for _, sheet := range p.Sheets {
+		layout, _ := p.Layout(sheet.Name)
+		if layout.HeaderRow <= 0 {
+			return fmt.Errorf("导入方案 [%s] 工作表 [%s] 表头行必须大于0", p.Name, sheet.Name)
+		}
+		if layout.AddressColumn == "" {
+			return fmt.Errorf("导入方案 [%s] 工作表 [%s] 未配置地址列", p.Name, sheet.Name)
+		}
+		switch layout.AddressFormat {
+		case AddressFormatBuildingRoom, AddressFormatBuildingUnitRoom, AddressFormatRoom, AddressFormatChinese:
+		default:
+			return fmt.Errorf("导入方案 [%s] 工作表 [%s] 地址格式错误: %s", p.Name, sheet.Name, layout.AddressFormat)
+		}
+	}
+	return nil
+}
+
+// DefaultImportProfile 内置导入方案（社区人口台账原有格式）
+func DefaultImportProfile() *ImportProfile {
+	return &ImportProfile{
+		Name:        DefaultImportProfileName,
+		Description: "社区人口台账（内置）",
+		SheetLayout: SheetLayout{
+			HeaderRow:     8,
+			DataStartRow:  9,
+			AddressColumn: "B",
+			AddressFormat: AddressFormatBuildingRoom,
+		},
+		Sheets: []SheetLayout{
+			{Name: "101楼"},
+			{Name: "103楼"},
+			{Name: "104楼新版"},
+			{Name: "105楼副本"},
+			{Name: "106楼副本"},
+			{Name: "108楼副本", AddressFormat: AddressFormatRoom},
+			{Name: "109楼-更新中"},
+			{Name: "110楼", AddressFormat: AddressFormatRoom},
+			{Name: "111楼新", AddressFormat: AddressFormatRoom},
+			{Name: "113楼", AddressFormat: AddressFormatRoom},
+			{Name: "114楼更新"},
+			{Name: "117楼", AddressFormat: AddressFormatChinese},
+			{Name: "118楼新版", AddressFormat: AddressFormatBuildingUnitRoom},
+			{Name: "119楼", AddressFormat: AddressFormatBuildingUnitRoom},
+			{Name: "120楼新版", AddressFormat: AddressFormatBuildingUnitRoom},
+			{Name: "121楼新版", AddressFormat: AddressFormatBuildingUnitRoom},
+			{Name: "122楼新版", AddressFormat: AddressFormatBuildingUnitRoom},
+		},
+	}
+}
+
+// loadImportProfiles 加载内置方案及目录下的所有 JSON 方案（同名方案以文件为准）
+func loadImportProfiles(dir string) (map[string]*ImportProfile, error) {
+	profiles := map[string]*ImportProfile{
+		DefaultImportProfileName: DefaultImportProfile(),
+	}
+	if dir == "" {
+		return profiles, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取导入方案失败 [%s]: %v", file, err)
+		}
+		var profile ImportProfile
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return nil, fmt.Errorf("解析导入方案失败 [%s]: %v", file, err)
+		}
+		if profile.Name == "" {
+			profile.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		if err := profile.validate(); err != nil {
+			return nil, err
+		}
+		profiles[profile.Name] = &profile
+	}
+	return profiles, nil
+}
+
+// GetImportProfile 获取导入方案，名称为空时使用内置方案
+// 每次调用都会重新读取方案目录，修改方案文件后无需重启服务
+func (s *UpdateExcelDataService) GetImportProfile(name string) (*ImportProfile, error) {
+	if name == "" {
+		name = DefaultImportProfileName
+	}
+	profiles, err := loadImportProfiles(s.profileDir)
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("导入方案不存在: %s", name)
+	}
+	return profile, nil
+}
+
+// ListImportProfiles 获取所有可用的导入方案
+func (s *UpdateExcelDataService) ListImportProfiles() ([]*ImportProfile, error) {
+	profiles, err := loadImportProfiles(s.profileDir)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ImportProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// ipFailures 单个IP的登录失败记录
+type ipFailures struct {
+	count   int
+	firstAt time.Time // 当前统计窗口的开始时间
+}
+
+// loginThrottle 按客户端IP统计登录失败次数（内存中，服务重启后清零）
+type loginThrottle struct {
+	mu       sync.Mutex
+	failures map[string]*ipFailures
+}
+
+var ipLoginThrottle = &loginThrottle{failures: make(map[string]*ipFailures)}
+
+// blocked IP 在当前统计窗口内的失败次数是否已达上限
+func (t *loginThrottle) blocked(ip string) bool {
+	if ip == "" || authConfig.MaxIPFailures <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.failures[ip]
+	if !ok {
+		return false
+	}
+	if time.Since(f.firstAt) > authConfig.IPFailureWindow {
+		delete(t.failures, ip)
+		return false
+	}
+	return f.count >= authConfig.MaxIPFailures
+}
+
+// fail 记录一次登录失败
+func (t *loginThrottle) fail(ip string) {
+	if ip == "" {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 记录过多时清理已过期的窗口
+	if len(t.failures) > 1000 {
+		for key, f := range t.failures {
+			if now.Sub(f.firstAt) > authConfig.IPFailureWindow {
+				delete(t.failures, key)
+			}
+		}
+	}
+
+	f, ok := t.failures[ip]
+	if !ok || now.Sub(f.firstAt) > authConfig.IPFailureWindow {
+		t.failures[ip] = &ipFailures{count: 1, firstAt: now}
+		return
+	}
+	f.count++
+}
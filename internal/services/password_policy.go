@@ -0,0 +1,241 @@
+package services
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"PLMS/internal/config"
+	"PLMS/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 密码策略规则
+const (
+	PasswordRuleMinLength   = "min_length"
+	PasswordRuleCharClasses = "char_classes"
+	PasswordRuleUsername    = "same_as_username"
+	PasswordRuleCommon      = "common_password"
+	PasswordRuleReused      = "reused"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords 常见弱密码（小写）
+var commonPasswords = func() map[string]bool {
+	result := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result[strings.ToLower(line)] = true
+	}
+	return result
+}()
+
+// passwordPolicy 密码策略（由 ConfigurePasswordPolicy 按配置设置）
+var passwordPolicy = config.PasswordConfig{
+	MinLength:      8,
+	MinCharClasses: 3,
+	HistorySize:    5,
+	MaxAgeDays:     90,
+}
+
+// ConfigurePasswordPolicy 设置密码策略，服务启动时调用；未配置（为 0）的项保留默认值
+// MaxAgeDays 为 0 表示密码永不过期
+func ConfigurePasswordPolicy(cfg config.PasswordConfig) {
+	if cfg.MinLength > 0 {
+		passwordPolicy.MinLength = cfg.MinLength
+	}
+	if cfg.MinCharClasses > 0 {
+		passwordPolicy.MinCharClasses = min(cfg.MinCharClasses, 4)
+	}
+	if cfg.HistorySize > 0 {
+		passwordPolicy.HistorySize = cfg.HistorySize
+	}
+	if cfg.MaxAgeDays >= 0 {
+		passwordPolicy.MaxAgeDays = cfg.MaxAgeDays
+	}
+}
+
+// PasswordViolation 违反的密码规则
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError 密码不符合策略，包含全部违反的规则
+type PasswordPolicyError struct {
+	Violations []PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "密码不符合要求：" + strings.Join(messages, "；")
+}
+
+// countCharClasses 统计密码包含的字符类别数（大写字母、小写字母、数字、符号）
+func countCharClasses(password string) int {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+// checkPasswordPolicy 按密码策略校验新密码
+// 参数:
+//   - userID: 用户ID，新建用户时为 0（不检查历史密码）
+//   - username: 用户名
+//   - password: 新密码
+//
+// 返回值:
+//   - error: 不符合策略时返回 *PasswordPolicyError
+func (s *AuthService) checkPasswordPolicy(userID int64, username, password string) error {
+	var violations []PasswordViolation
+	add := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if len([]rune(password)) < passwordPolicy.MinLength {
+		add(PasswordRuleMinLength, fmt.Sprintf("长度不能少于 %d 位", passwordPolicy.MinLength))
+	}
+	if countCharClasses(password) < passwordPolicy.MinCharClasses {
+		add(PasswordRuleCharClasses, fmt.Sprintf("需包含大写字母、小写字母、数字、符号中的至少 %d 类", passwordPolicy.MinCharClasses))
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		add(PasswordRuleUsername, "不能包含用户名")
+	}
+	if commonPasswords[strings.ToLower(password)] {
+		add(PasswordRuleCommon, "不能使用常见弱密码")
+	}
+
+	if userID > 0 {
+		reused, err := s.isRecentPassword(userID, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			add(PasswordRuleReused, fmt.Sprintf("不能与最近 %d 次使用过的密码相同", passwordPolicy.HistorySize))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isRecentPassword 新密码是否与当前密码或最近 HistorySize 次的密码相同
+func (s *AuthService) isRecentPassword(userID int64, password string) (bool, error) {
+	var user models.SysUser
+	if err := s.db.Select("password").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	hashes := []string{user.Password}
+
+	var history []string
+	if err := s.db.Model(&models.SysPasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(passwordPolicy.HistorySize).
+		Pluck("password_hash", &history).Error; err != nil {
+		return false, err
+	}
+	hashes = append(hashes, history...)
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// savePasswordHistory 记录用户新设置的密码，只保留最近 HistorySize 条
+func (s *AuthService) savePasswordHistory(tx *gorm.DB, userID int64, passwordHash string) error {
+	if err := tx.Create(&models.SysPasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+		return err
+	}
+
+	var keepIDs []int64
+	if err := tx.Model(&models.SysPasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(passwordPolicy.HistorySize).Pluck("id", &keepIDs).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, keepIDs).Delete(&models.SysPasswordHistory{}).Error
+}
+
+// IsPasswordPolicyError 判断是否为密码策略错误
+func IsPasswordPolicyError(err error) (*PasswordPolicyError, bool) {
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr, true
+	}
+	return nil, false
+}
+
+// PasswordStatus 用户密码状态
+type PasswordStatus struct {
+	IsDefaultPassword bool       `json:"isDefaultPassword"`
+	PasswordExpired   bool       `json:"passwordExpired"`
+	ExpiresAt         *time.Time `json:"passwordExpiresAt"` // 为空表示永不过期
+	DaysUntilExpiry   *int       `json:"daysUntilExpiry"`   // 为空表示永不过期，已过期时为 0
+}
+
+// RequireChangePassword 是否需要先修改密码（默认密码或密码已过期）
+func (p *PasswordStatus) RequireChangePassword() bool {
+	return p.IsDefaultPassword || p.PasswordExpired
+}
+
+// GetPasswordStatus 获取用户的默认密码及密码过期状态
+// 未记录修改时间的用户按创建时间计算
+func (s *AuthService) GetPasswordStatus(userID int64) (*PasswordStatus, error) {
+	var user models.SysUser
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	status := &PasswordStatus{IsDefaultPassword: user.IsDefaultPassword == 1}
+	if passwordPolicy.MaxAgeDays <= 0 {
+		return status, nil
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	expiresAt := changedAt.AddDate(0, 0, passwordPolicy.MaxAgeDays)
+	days := int(math.Ceil(time.Until(expiresAt).Hours() / 24))
+	if days <= 0 {
+		days = 0
+		status.PasswordExpired = true
+	}
+	status.ExpiresAt = &expiresAt
+	status.DaysUntilExpiry = &days
+	return status, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"PLMS/internal/config"
+)
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	saved := passwordPolicy
+	defer func() { passwordPolicy = saved }()
+	passwordPolicy = config.PasswordConfig{MinLength: 8, MinCharClasses: 3, HistorySize: 5, MaxAgeDays: 90}
+
+	// userID 为 0 时不检查历史密码，不需要数据库
+	s := &AuthService{}
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		wantRules []string
+	}{
+		{name: "符合策略", username: "zhangsan", password: "Plms#2024x"},
+		{name: "三类字符", username: "zhangsan", password: "plms2024!"},
+		{name: "长度不足", username: "zhangsan", password: "Ab1!xyz", wantRules: []string{PasswordRuleMinLength}},
+		{name: "字符类别不足", username: "zhangsan", password: "plmsplms2024", wantRules: []string{PasswordRuleCharClasses}},
+		{name: "包含用户名（不区分大小写）", username: "zhangsan", password: "ZhangSan#2024", wantRules: []string{PasswordRuleUsername}},
+		{name: "常见弱密码", username: "zhangsan", password: "Admin@123", wantRules: []string{PasswordRuleCommon}},
+		{name: "常见弱密码（不区分大小写）", username: "zhangsan", password: "P@ssw0rd", wantRules: []string{PasswordRuleCommon}},
+		{name: "违反多条规则", username: "admin", password: "admin", wantRules: []string{PasswordRuleMinLength, PasswordRuleCharClasses, PasswordRuleUsername, PasswordRuleCommon}},
+		{name: "按字符而非字节计算长度", username: "zhangsan", password: "密码Ab1!中文", wantRules: nil},
+		{name: "中文不足8个字符", username: "zhangsan", password: "密码Ab1!", wantRules: []string{PasswordRuleMinLength}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkPasswordPolicy(0, tt.username, tt.password)
+			if len(tt.wantRules) == 0 {
+				if err != nil {
+					t.Fatalf("checkPasswordPolicy(%q) 返回错误: %v", tt.password, err)
+				}
+				return
+			}
+			policyErr, ok := IsPasswordPolicyError(err)
+			if !ok {
+				t.Fatalf("checkPasswordPolicy(%q) = %v, 应返回 *PasswordPolicyError", tt.password, err)
+			}
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !slices.Equal(rules, tt.wantRules) {
+				t.Errorf("checkPasswordPolicy(%q) 违反规则 = %v, want %v", tt.password, rules, tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestCountCharClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{password: "", want: 0},
+		{password: "abc", want: 1},
+		{password: "abcABC", want: 2},
+		{password: "abcABC123", want: 3},
+		{password: "abcABC123!", want: 4},
+		{password: "中文123", want: 2},
+	}
+	for _, tt := range tests {
+		if got := countCharClasses(tt.password); got != tt.want {
+			t.Errorf("countCharClasses(%q) = %d, want %d", tt.password, got, tt.want)
+		}
+	}
+}
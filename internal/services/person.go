@@ -1,30 +1,91 @@
 package services
 
 import (
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/idcard"
 	"PLMS/internal/models"
+	"errors"
 	"fmt"
 	"github.com/ahmetb/go-linq/v3"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 )
 
 type PersonService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	access *UserAccess // 当前用户的权限范围，nil 表示不限制
 }
 
 func NewPersonService(db *gorm.DB) *PersonService {
 	return &PersonService{db: db}
 }
 
+// WithAccess 返回按指定用户权限范围查询的服务
+func (p *PersonService) WithAccess(access *UserAccess) *PersonService {
+	return &PersonService{db: p.db, access: access}
+}
+
+// canViewSensitive 是否可以查看身份证号、电话等敏感信息明文
+func (p *PersonService) canViewSensitive() bool {
+	return p.access.HasPermission(models.PermViewSensitive)
+}
+
+// maskPersons 无查看敏感信息权限时将敏感字段脱敏
+func (p *PersonService) maskPersons(persons []models.Person) {
+	if p.canViewSensitive() {
+		return
+	}
+	for i := range persons {
+		persons[i].MaskSensitive()
+	}
+}
+
+// sensitiveCondition 敏感字段的查询条件
+// 身份证号、电话加密存储，只能通过盲索引精确匹配；同时匹配明文以兼容尚未迁移的数据
+func sensitiveCondition(column, value string) clause.Expression {
+	return clause.Or(
+		clause.Eq{Column: clause.Column{Name: column + "_bidx"}, Value: fieldcrypt.BlindIndex(value)},
+		clause.Eq{Column: clause.Column{Name: column}, Value: value},
+	)
+}
+
+// normalizeIDCard 有效的身份证号转换为18位大写格式，与保存的号码保持一致
+func normalizeIDCard(value string) string {
+	if info, err := idcard.Parse(value); err == nil {
+		return info.Number
+	}
+	return value
+}
+
+// personAgeSQL 按出生日期计算的当前周岁，出生日期未知时使用录入的年龄
+const personAgeSQL = "(CASE WHEN birth_date IS NOT NULL THEN TIMESTAMPDIFF(YEAR, birth_date, CURDATE()) ELSE age END)"
+
+// ageRangeCondition 年龄范围条件：有出生日期时按出生日期计算，否则按录入的年龄
+// 年龄在 [minAge, maxAge] 之间即出生日期在 (now-(maxAge+1)年, now-minAge年] 之间
+func ageRangeCondition(minAge, maxAge int, now time.Time) clause.Expression {
+	return clause.Expr{
+		SQL: "((birth_date > ? AND birth_date <= ?) OR (birth_date IS NULL AND age >= ? AND age <= ?))",
+		Vars: []interface{}{
+			now.AddDate(-(maxAge + 1), 0, 0).Format("2006-01-02"),
+			now.AddDate(-minAge, 0, 0).Format("2006-01-02"),
+			minAge, maxAge,
+		},
+	}
+}
+
 // GetBuildingNumbers 是 PersonService 的一个方法，用于获取所有不重复的楼号
 // 返回一个字符串切片和可能的错误
 func (p *PersonService) GetBuildingNumbers() ([]string, error) {
 	// 初始化一个空的字符串切片用于存储建筑编号
 	var buildingNumbers []string
-	// 使用数据库查询，从 Person 表中获取不重复的 building_number 字段
+	// 从楼栋表中获取未删除的楼号（包括还没有住户的楼栋）
 	// 并将查询结果填充到 buildingNumbers 切片中
-	err := p.db.Model(&models.Person{}).Where("is_del", 0).Not("building_number = ?", "").Distinct("building_number").Pluck("building_number", &buildingNumbers).Error
+	err := p.access.scopeQuery(p.db.Model(&models.Building{})).Where("is_del = 0").
+		Order("CAST(building_number AS UNSIGNED), building_number").Pluck("building_number", &buildingNumbers).Error
 	// 返回建筑编号切片和可能的错误
 	return buildingNumbers, err
 }
@@ -38,8 +99,14 @@ func (p *PersonService) GetBuildingNumbers() ([]string, error) {
 //   - error: 可能发生的错误信息
 func (p *PersonService) GetUnitNumbersByBuildingNumber(buildingNumber string) ([]int, error) {
 	var unitNumbers []int // 用于存储查询结果的单元号切片
-	// 执行数据库查询，从Person表中查询指定楼栋号的所有不重复的单元号
-	err := p.db.Model(&models.Person{}).Where("is_del", 0).Where("building_number = ?", buildingNumber).Distinct("unit_number").Pluck("unit_number", &unitNumbers).Error
+	// 执行数据库查询，从单元表中查询指定楼栋号的所有单元号
+	if !p.access.CanAccessBuilding(buildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+	err := p.db.Model(&models.Unit{}).
+		Joins("JOIN building ON building.id = unit.building_id").
+		Where("building.building_number = ? AND building.is_del = 0 AND unit.is_del = 0", buildingNumber).
+		Order("unit.unit_number").Pluck("unit.unit_number", &unitNumbers).Error
 	return unitNumbers, err // 返回查询结果和可能的错误
 }
 
@@ -53,6 +120,8 @@ func (p *PersonService) GetUnitNumbersByBuildingNumber(buildingNumber string) ([
 func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFilter) *gorm.DB {
 	// 先处理 is_del 条件
 	query = query.Where("is_del = ? and building_number<>'' and unit_number<>0 and room_number<>''", 0)
+	// 限制在当前用户的楼栋范围内
+	query = p.access.scopeQuery(query)
 
 	// 如果 QueryType == 1，使用 OR 组合所有条件
 	if filter.QueryType == 1 {
@@ -102,18 +171,12 @@ func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFil
 
 		// 身份证号条件查询
 		if filter.IDCard != "" && filter.IDCard != "0" {
-			orConditions = append(orConditions, clause.Like{
-				Column: clause.Column{Name: "id_card"},
-				Value:  "%" + filter.IDCard + "%",
-			})
+			orConditions = append(orConditions, sensitiveCondition("id_card", normalizeIDCard(filter.IDCard)))
 		}
 
 		// 年龄范围条件查询
 		if len(filter.Age) > 2 {
-			orConditions = append(orConditions, clause.And(
-				clause.Gte{Column: clause.Column{Name: "age"}, Value: filter.Age[0]},
-				clause.Lte{Column: clause.Column{Name: "age"}, Value: filter.Age[1]},
-			))
+			orConditions = append(orConditions, ageRangeCondition(filter.Age[0], filter.Age[1], time.Now()))
 		}
 
 		// 性别条件查询
@@ -164,10 +227,7 @@ func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFil
 		}
 
 		if filter.Telephone != "" {
-			orConditions = append(orConditions, clause.Like{
-				Column: clause.Column{Name: "telephone"},
-				Value:  "%" + filter.Telephone + "%",
-			})
+			orConditions = append(orConditions, sensitiveCondition("telephone", filter.Telephone))
 		}
 
 		if filter.HasElectricCar != 0 {
@@ -304,7 +364,7 @@ func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFil
 
 		// 身份证号条件查询
 		if filter.IDCard != "" && filter.IDCard != "0" {
-			query = query.Where("id_card LIKE ?", "%"+filter.IDCard+"%")
+			query = query.Where(sensitiveCondition("id_card", normalizeIDCard(filter.IDCard)))
 		}
 
 		// 年龄范围条件查询
@@ -312,7 +372,7 @@ func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFil
 			minAge := filter.Age[0]
 			maxAge := filter.Age[1]
 			if minAge < maxAge && maxAge != 0 {
-				query = query.Where("age >= ? AND age <= ?", filter.Age[0], filter.Age[1])
+				query = query.Where(ageRangeCondition(minAge, maxAge, time.Now()))
 			}
 		}
 
@@ -346,7 +406,7 @@ func (p *PersonService) buildPersonQuery(query *gorm.DB, filter models.PersonFil
 		}
 
 		if filter.Telephone != "" {
-			query = query.Where("telephone LIKE ?", "%"+filter.Telephone+"%")
+			query = query.Where(sensitiveCondition("telephone", filter.Telephone))
 		}
 
 		if filter.HasElectricCar != 0 {
@@ -430,9 +490,15 @@ func (p *PersonService) GetPersonInfo(id int) (models.PersonInfo, error) {
 	var person models.Person
 	var bicycles []models.ElectricBicycle
 	var personInfo models.PersonInfo
-	result := p.db.First(&person, id)
+	result := p.access.scopeQuery(p.db).First(&person, id)
+	if result.Error != nil {
+		return personInfo, result.Error
+	}
+	if !p.canViewSensitive() {
+		person.MaskSensitive()
+	}
 	personInfo.Person = person
-	result = p.db.Model(&models.ElectricBicycle{}).Where("person_id=?", id).Find(&bicycles)
+	result = p.db.Model(&models.ElectricBicycle{}).Where("person_id=? and is_del=0", id).Find(&bicycles)
 	personInfo.Bicycles = bicycles
 	return personInfo, result.Error
 }
@@ -441,12 +507,16 @@ func (p *PersonService) GetPersonInfoByRoom(buildingNumber, unitNumber, roomNumb
 	var persons []models.Person
 	var bicycles []models.ElectricBicycle
 	var personInfos []models.PersonInfo
+	if !p.access.CanAccessBuilding(buildingNumber) {
+		return nil, errNoBuildingAccess
+	}
 	result := p.db.Where("building_number=? and unit_number=? and room_number=?",
 		buildingNumber, unitNumber, roomNumber).Find(&persons)
+	p.maskPersons(persons)
 	for _, person := range persons {
 		personInfo := models.PersonInfo{}
 		personInfo.Person = person
-		result = p.db.Model(&models.ElectricBicycle{}).Where("person_id=?", person.ID).Find(&bicycles)
+		result = p.db.Model(&models.ElectricBicycle{}).Where("person_id=? and is_del=0", person.ID).Find(&bicycles)
 		personInfo.Bicycles = bicycles
 		personInfos = append(personInfos, personInfo)
 	}
@@ -479,70 +549,103 @@ func (p *PersonService) GetPersons(filter models.PersonFilter) ([]models.Person,
 	query = p.buildPageQuery(query, filter) // 根据过滤条件添加分页
 
 	result := query.Find(&persons)      // 执行查询
+	p.maskPersons(persons)              // 无权限时敏感字段脱敏
 	return persons, total, result.Error // 返回查询结果、总记录数和可能的错误
 }
 
+// hasPersonCriteria 筛选条件中是否包含楼号、单元、房号以外的人员条件
+func hasPersonCriteria(filter models.PersonFilter) bool {
+	location := models.PersonFilter{
+		BuildingNumber: filter.BuildingNumber,
+		UnitNumber:     filter.UnitNumber,
+		RoomNumber:     filter.RoomNumber,
+		QueryType:      filter.QueryType,
+		Page:           filter.Page,
+		PageSize:       filter.PageSize,
+	}
+	filter.ShowFields = nil
+	if len(filter.Age) < 2 {
+		filter.Age = nil
+	}
+	if filter.IDCard == "0" {
+		filter.IDCard = ""
+	}
+	return !reflect.DeepEqual(filter, location)
+}
+
+// GetRooms 获取房间列表及住户概况
+// 只按楼号、单元、房号筛选时从房间表查询，包括没有住户的房间；
+// 包含其他人员条件时只列出有符合条件住户的房间
 func (p *PersonService) GetRooms(filter models.PersonFilter) ([]models.RoomList, int64, error) {
 	var roomList = []models.RoomList{}
 	var total int64
-	query := p.db.Table("person").
-		Select("building_number, unit_number, room_number")
-	query = p.buildPersonQuery(query, filter)
-	query.Where("building_number<>'' and unit_number>0 and room_number<>''")
-	query.Group("building_number, unit_number, room_number")
-	//query.Order("building_number, unit_number, room_number")
-	query.Order(`
-    CAST(building_number AS UNSIGNED), building_number,
-    CAST(unit_number AS UNSIGNED), unit_number,
-    CAST(room_number AS UNSIGNED), room_number
-`)
+	filtered := func() *gorm.DB {
+		query := p.db.Table("room").
+			Joins("JOIN unit ON unit.id = room.unit_id").
+			Joins("JOIN building ON building.id = room.building_id").
+			Where("room.is_del = 0 AND unit.is_del = 0 AND building.is_del = 0")
+		query = p.access.scopeColumn(query, "building.building_number")
+		if hasPersonCriteria(filter) {
+			return query.Where("room.id IN (?)", p.buildPersonQuery(p.db.Model(&models.Person{}).Select("room_id"), filter))
+		}
+		if filter.BuildingNumber != "" {
+			query = query.Where("building.building_number = ?", filter.BuildingNumber)
+		}
+		if filter.UnitNumber != 0 {
+			query = query.Where("unit.unit_number = ?", filter.UnitNumber)
+		}
+		if filter.RoomNumber != "" {
+			query = query.Where("room.room_number = ?", filter.RoomNumber)
+		}
+		return query
+	}
 	// 计算总记录数
-	if err := query.Count(&total).Error; err != nil {
+	if err := filtered().Count(&total).Error; err != nil {
 		return nil, 0, err // 如果计数出错，返回错误
 	}
+	query := filtered().
+		Select("room.id AS room_id, building.building_number, unit.unit_number, room.room_number, room.floor, room.area, room.property_type").
+		Order(`
+    CAST(building.building_number AS UNSIGNED), building.building_number,
+    unit.unit_number,
+    CAST(room.room_number AS UNSIGNED), room.room_number`)
 	query = p.buildPageQuery(query, filter) // 根据过滤条件添加分页
-	result := query.Find(&roomList)         // 执行查询
-	var persons = []models.Person{}
-	query = p.db.Model(&models.Person{})
-	query.Where("is_del=0")
+	if err := query.Scan(&roomList).Error; err != nil {
+		return nil, 0, err
+	}
 
-	var orConditions []clause.Expression
+	var roomIDs []int64
 	for _, room := range roomList {
-		orConditions = append(orConditions, clause.And(
-			clause.Eq{Column: "building_number", Value: room.BuildingNumber},
-			clause.Eq{Column: "unit_number", Value: room.UnitNumber},
-			clause.Eq{Column: "room_number", Value: room.RoomNumber},
-		))
-	}
-	query.Where(clause.Or(orConditions...))
-	query.Find(&persons)
+		roomIDs = append(roomIDs, room.RoomID)
+	}
+	var persons = []models.Person{}
+	if len(roomIDs) > 0 {
+		if err := p.db.Where("is_del = 0 AND room_id IN ?", roomIDs).Find(&persons).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	roomPersons := make(map[int64][]models.Person)
+	for _, person := range persons {
+		roomPersons[*person.RoomID] = append(roomPersons[*person.RoomID], person)
+	}
 	for i := range roomList {
 		room := &roomList[i]
 		//人员信息 如果人员里面有租户，就写第一个租户的名字，如果没有，就看有没有包含业的第一个名字，如果都没有，就歇第一个名字
 		var rentData = []models.Person{}
-		linq.From(persons).Where(func(p interface{}) bool {
-			person := p.(models.Person)
-			return person.BuildingNumber == room.BuildingNumber && person.UnitNumber == room.UnitNumber && person.RoomNumber == room.RoomNumber
-		}).Where(func(p interface{}) bool {
+		linq.From(roomPersons[room.RoomID]).Where(func(p interface{}) bool {
 			return strings.Contains(p.(models.Person).HousingSituation, "租")
 		}).OrderBy(func(p interface{}) interface{} {
 			return p.(models.Person).ID
 		}).ToSlice(&rentData)
 		var ownerData = []models.Person{}
-		linq.From(persons).Where(func(p interface{}) bool {
-			person := p.(models.Person)
-			return person.BuildingNumber == room.BuildingNumber && person.UnitNumber == room.UnitNumber && person.RoomNumber == room.RoomNumber
-		}).Where(func(p interface{}) bool {
+		linq.From(roomPersons[room.RoomID]).Where(func(p interface{}) bool {
 			return !strings.Contains(p.(models.Person).HousingSituation, "租") &&
 				!strings.Contains(p.(models.Person).HousingSituation, "空")
 		}).OrderBy(func(p interface{}) interface{} {
 			return p.(models.Person).ID
 		}).ToSlice(&ownerData)
 		var emptyData = []models.Person{}
-		linq.From(persons).Where(func(p interface{}) bool {
-			person := p.(models.Person)
-			return person.BuildingNumber == room.BuildingNumber && person.UnitNumber == room.UnitNumber && person.RoomNumber == room.RoomNumber
-		}).Where(func(p interface{}) bool {
+		linq.From(roomPersons[room.RoomID]).Where(func(p interface{}) bool {
 			return strings.Contains(p.(models.Person).HousingSituation, "空")
 		}).OrderBy(func(p interface{}) interface{} {
 			return p.(models.Person).ID
@@ -562,9 +665,15 @@ func (p *PersonService) GetRooms(filter models.PersonFilter) ([]models.RoomList,
 				room.PersonNum = len(ownerData)
 				room.HousingSituation = "自住"
 			}
+		} else if len(roomPersons[room.RoomID]) == 0 {
+			// 没有住户的房间
+			room.HousingSituation = "空置"
+		}
+		if !p.canViewSensitive() {
+			room.Telephone = models.MaskPhone(room.Telephone)
 		}
 	}
-	return roomList, total, result.Error
+	return roomList, total, nil
 }
 
 // PersonService 结构体的方法，获取人口统计信息
@@ -576,13 +685,17 @@ func (p *PersonService) GetRooms(filter models.PersonFilter) ([]models.RoomList,
 //   - error: 错误信息
 func (p *PersonService) GetPersonStatistics(buildingNumber string) (*models.PersonStatistic, error) {
 	var result models.PersonStatistic
-	// SQL查询语句，用于统计各类人口信息
+	cond, condParams, err := p.statisticsCondition(buildingNumber)
+	if err != nil {
+		return nil, err
+	}
+	// SQL查询语句，用于统计各类人口信息（/*cond*/ 处替换为楼号及楼栋范围条件）
 	sql := `SELECT
     COUNT(*) AS total_households,
 
     -- 人口统计
-    (SELECT COUNT(*) FROM person WHERE is_del = 0 AND is_permanent = 1) AS permanent_population,
-    (SELECT COUNT(*) FROM person WHERE is_del = 0 AND is_permanent = 2) AS floating_population,
+    (SELECT COUNT(*) FROM person WHERE is_del = 0 /*cond*/ AND is_permanent = 1) AS permanent_population,
+    (SELECT COUNT(*) FROM person WHERE is_del = 0 /*cond*/ AND is_permanent = 2) AS floating_population,
 
     -- 房屋统计
     SUM(CASE
@@ -601,50 +714,18 @@ FROM (
          SELECT building_number, unit_number, room_number, 
                 MAX(housing_situation) as housing_situation
          FROM person
-         WHERE is_del = 0 
+         WHERE is_del = 0 /*cond*/
          GROUP BY building_number, unit_number, room_number
      ) AS rooms
 `
+	sql = strings.ReplaceAll(sql, "/*cond*/", cond)
+	// 条件出现三次，参数也要重复三次
 	params := []interface{}{}
-	// 动态添加楼号条件
-	if buildingNumber != "" && buildingNumber != "0" {
-		sql = `SELECT
-    COUNT(*) AS total_households,
-
-    -- 人口统计
-    (SELECT COUNT(*) FROM person WHERE is_del = 0 AND building_number = ? AND is_permanent = 1) AS permanent_population,
-    (SELECT COUNT(*) FROM person WHERE is_del = 0 AND building_number = ? AND is_permanent = 2) AS floating_population,
-
-    -- 房屋统计
-    SUM(CASE
-            WHEN housing_situation NOT LIKE '%租%'
-                AND housing_situation NOT LIKE '%空%'
-                AND housing_situation NOT LIKE '%装修%'
-                THEN 1 ELSE 0
-        END) AS self_occupied_houses,
-
-    SUM(CASE WHEN housing_situation LIKE '%租%' THEN 1 ELSE 0 END) AS rented_houses,
-    SUM(CASE WHEN housing_situation LIKE '%空%' THEN 1 ELSE 0 END) AS vacant_houses,
-    SUM(CASE WHEN housing_situation LIKE '%装修%' THEN 1 ELSE 0 END) AS decoration_houses
-
-FROM (
-         -- 三个字段组合才是唯一房间
-         SELECT building_number, unit_number, room_number, 
-                MAX(housing_situation) as housing_situation
-         FROM person
-         WHERE is_del = 0 
-           AND building_number = ?  -- 这里也要加条件！
-         GROUP BY building_number, unit_number, room_number
-     ) AS rooms
-`
-		//params = append([]interface{}{buildingNumber}, params...)
-		params = append(params, buildingNumber)
-		params = append(params, buildingNumber)
-		params = append(params, buildingNumber)
-		//fmt.Println(params)
+	for i := 0; i < 3; i++ {
+		params = append(params, condParams...)
 	}
 	// 执行SQL查询并将结果扫描到result结构体中
-	err := p.db.Raw(sql, params...).Scan(&result).Error
+	err = p.db.Raw(sql, params...).Scan(&result).Error
 	if err != nil {
 		return nil, err // 查询出错时返回错误
 	}
@@ -667,7 +748,7 @@ FROM (
 		result.DecorationHousesPercent = fmt.Sprintf("%.2f%%", float64(result.DecorationHouses)/float64(result.TotalHouseholds)*100)
 	}
 
-	stat2, err := p.getPersonDemographicStats(buildingNumber)
+	stat2, err := p.getPersonDemographicStats(cond, condParams)
 	if err == nil {
 		result.RegisteredDist = stat2.RegisteredResidenceTypeStats
 		result.AgeDist = stat2.AgeDist
@@ -676,7 +757,30 @@ FROM (
 
 	return &result, nil // 返回查询结果
 }
-func (p *PersonService) getPersonDemographicStats(buildingNumber string) (*models.PersonDemographicStat, error) {
+
+// statisticsCondition 统计查询的楼号及楼栋范围条件
+// 参数:
+//   - buildingNumber: 楼号，为空或"0"时不限制楼号
+//
+// 返回值:
+//   - string: 以 AND 开头的SQL条件
+//   - []interface{}: 条件参数
+//   - error: 楼号不在当前用户范围内时返回错误
+func (p *PersonService) statisticsCondition(buildingNumber string) (string, []interface{}, error) {
+	cond := ""
+	var params []interface{}
+	if buildingNumber != "" && buildingNumber != "0" {
+		if !p.access.CanAccessBuilding(buildingNumber) {
+			return "", nil, errNoBuildingAccess
+		}
+		cond += " AND building_number = ?"
+		params = append(params, buildingNumber)
+	}
+	scopeCond, scopeParams := p.access.scopeSQL()
+	return cond + scopeCond, append(params, scopeParams...), nil
+}
+
+func (p *PersonService) getPersonDemographicStats(cond string, params []interface{}) (*models.PersonDemographicStat, error) {
 	var result models.PersonDemographicStat
 
 	// 初始化map
@@ -699,20 +803,14 @@ func (p *PersonService) getPersonDemographicStats(buildingNumber string) (*model
         SUM(CASE WHEN is_empty_nest = 1 THEN 1 ELSE 0 END) as empty_nest,
         
         -- 年龄段统计
-        SUM(CASE WHEN age >= 60 AND age <= 80 THEN 1 ELSE 0 END) as age60_to80,
-        SUM(CASE WHEN age > 80 THEN 1 ELSE 0 END) as age_over80,
+        SUM(CASE WHEN ` + personAgeSQL + ` BETWEEN 60 AND 80 THEN 1 ELSE 0 END) as age60_to80,
+        SUM(CASE WHEN ` + personAgeSQL + ` > 80 THEN 1 ELSE 0 END) as age_over80,
         
         -- 总人数
         COUNT(*) as total
     FROM person
     WHERE is_del = 0
-    `
-
-	var params []interface{}
-	if buildingNumber != "" && buildingNumber != "0" {
-		sql += " AND building_number = ?"
-		params = append(params, buildingNumber)
-	}
+    ` + cond
 
 	var stats struct {
 		Type1       int64
@@ -765,7 +863,273 @@ func (p *PersonService) ExportPersons(filter models.PersonFilter) ([]models.Pers
     CAST(unit_number AS UNSIGNED), unit_number,
     CAST(room_number AS UNSIGNED), room_number`)
 
-	// 不分页，查询所有数据
+	// 不分页，查询所有数据；无权限时敏感字段脱敏，导出值同样为脱敏值
 	result := query.Find(&persons)
+	p.maskPersons(persons)
 	return persons, result.Error
 }
+
+// validatePerson 校验人员信息字段
+// 参数:
+//   - person: 待校验的人员信息
+//   - strictIDCard: 是否严格校验身份证号；为 false 时身份证号无效不报错，只是不按身份证号设置出生日期等
+//
+// 返回值:
+//   - error: 校验不通过时返回错误信息
+func validatePerson(person *models.Person, strictIDCard bool) error {
+	person.BuildingNumber = strings.TrimSpace(person.BuildingNumber)
+	person.RoomNumber = strings.TrimSpace(person.RoomNumber)
+	person.Name = strings.TrimSpace(person.Name)
+	person.IDCard = strings.TrimSpace(person.IDCard)
+	person.Telephone = strings.TrimSpace(person.Telephone)
+	person.ElderContactPhone = strings.TrimSpace(person.ElderContactPhone)
+
+	if person.BuildingNumber == "" {
+		return errors.New("楼号不能为空")
+	}
+	if person.UnitNumber <= 0 {
+		return errors.New("单元号必须大于0")
+	}
+	if person.RoomNumber == "" {
+		return errors.New("房号不能为空")
+	}
+	if person.Name == "" {
+		return errors.New("姓名不能为空")
+	}
+	if models.IsMasked(person.IDCard) || models.IsMasked(person.Telephone) || models.IsMasked(person.ElderContactPhone) {
+		return errors.New("身份证号、联系方式不能包含*")
+	}
+	now := time.Now()
+	if person.IDCard != "" && strictIDCard {
+		if _, err := idcard.Parse(person.IDCard); err != nil {
+			return err
+		}
+	}
+	// 出生日期、性别、年龄以有效的身份证号为准
+	if !person.ApplyIDCard(now) && person.BirthDate != nil {
+		if person.BirthDate.After(now) || person.BirthDate.Year() < 1900 {
+			return errors.New("出生日期错误")
+		}
+		person.Age = idcard.AgeAt(*person.BirthDate, now)
+	}
+	if person.Age < 0 || person.Age > 150 {
+		return errors.New("年龄必须在0到150之间")
+	}
+	if person.Gender < 0 || person.Gender > 2 {
+		return errors.New("性别取值错误：0未知，1男，2女")
+	}
+	if person.RegisteredResidenceType < 0 || person.RegisteredResidenceType > 3 {
+		return errors.New("户籍情况取值错误：0未知，1东湖户籍，2北京市其他户籍，3外地户籍")
+	}
+	if len(person.Telephone) > 50 {
+		return errors.New("联系方式长度不能超过50位")
+	}
+	if len(person.ElderContactPhone) > 20 {
+		return errors.New("紧急联系电话长度不能超过20位")
+	}
+
+	// 是否类字段：0未知，1是，2否
+	yesNoFields := []struct {
+		field string
+		val   int
+	}{
+		{"is_permanent", person.IsPermanent},
+		{"has_electric_car", person.HasElectricCar},
+		{"is_low_income", person.IsLowIncome},
+		{"is_low_income2", person.IsLowIncome2},
+		{"is_destitute", person.IsDestitute},
+		{"is_family_planning_special", person.IsFamilyPlanningSpecial},
+		{"is_living_alone", person.IsLivingAlone},
+		{"is_empty_nest", person.IsEmptyNest},
+		{"is_orphaned", person.IsOrphaned},
+		{"is_needs_focus", person.IsNeedsFocus},
+		{"is_in_group", person.IsInGroup},
+		{"is_private_message", person.IsPrivateMessage},
+		{"has_pet", person.HasPet},
+	}
+	for _, f := range yesNoFields {
+		if f.val < 0 || f.val > 2 {
+			return fmt.Errorf("%s取值错误：0未知，1是，2否", models.GetExportFieldHeader(f.field))
+		}
+	}
+	if person.IsCp != 0 && person.IsCp != 1 {
+		return errors.New("是否党员取值错误：0否，1是")
+	}
+	if person.CpJoiningDay != nil && *person.CpJoiningDay == "" {
+		person.CpJoiningDay = nil
+	}
+	return nil
+}
+
+// getActivePerson 查询未删除的人员
+func (p *PersonService) getActivePerson(id int64) (*models.Person, error) {
+	var person models.Person
+	if err := p.access.scopeQuery(p.db).Where("id = ? AND is_del = 0", id).First(&person).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("人员不存在")
+		}
+		return nil, err
+	}
+	return &person, nil
+}
+
+// GetPerson 获取未删除的人员（限制在用户的楼栋范围内，无权限时敏感字段脱敏）
+func (p *PersonService) GetPerson(id int64) (*models.Person, error) {
+	person, err := p.getActivePerson(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.canViewSensitive() {
+		person.MaskSensitive()
+	}
+	return person, nil
+}
+
+// RevealSensitive 获取单个人员的敏感信息明文（需查看敏感信息权限，调用方负责记录审计日志）
+func (p *PersonService) RevealSensitive(id int64) (*models.PersonSensitiveInfo, error) {
+	if !p.canViewSensitive() {
+		return nil, errors.New("无权查看敏感信息")
+	}
+	person, err := p.getActivePerson(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PersonSensitiveInfo{
+		ID:                person.ID,
+		IDCard:            person.IDCard,
+		Telephone:         person.Telephone,
+		ElderContactPhone: person.ElderContactPhone,
+	}, nil
+}
+
+// CreatePerson 新增人员
+// 参数:
+//   - person: 人员信息（ID、删除标记、创建时间由系统维护）
+//
+// 返回值:
+//   - *models.Person: 保存后的人员信息
+//   - error: 错误信息
+func (p *PersonService) CreatePerson(person *models.Person) (*models.Person, error) {
+	if err := p.fillRoomAddress(person); err != nil {
+		return nil, err
+	}
+	if err := validatePerson(person, true); err != nil {
+		return nil, err
+	}
+	if !p.access.CanAccessBuilding(person.BuildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+	person.ID = 0
+	person.IsDel = 0
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		roomID, err := newRoomResolver(tx).resolve(person.BuildingNumber, person.UnitNumber, person.RoomNumber)
+		if err != nil {
+			return err
+		}
+		person.RoomID = roomID
+		if err := tx.Create(person).Error; err != nil {
+			return errors.New("新增人员失败: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.GetPerson(person.ID)
+}
+
+// fillRoomAddress 指定了所属房间时，按房间填写楼号、单元、房号
+func (p *PersonService) fillRoomAddress(person *models.Person) error {
+	if person.RoomID == nil || *person.RoomID <= 0 {
+		person.RoomID = nil
+		return nil
+	}
+	room, err := NewHousingService(p.db).WithAccess(p.access).GetRoom(*person.RoomID)
+	if err != nil {
+		return err
+	}
+	person.BuildingNumber = room.BuildingNumber
+	person.UnitNumber = room.UnitNumber
+	person.RoomNumber = room.RoomNumber
+	return nil
+}
+
+// UpdatePerson 更新人员信息（只更新请求中包含的字段，已删除的人员不可更新）
+// 参数:
+//   - id: 人员ID
+//   - person: 请求中的人员信息
+//   - fields: 请求中包含的字段（json 名称），未包含的字段保持原值
+//
+// 返回值:
+//   - *models.Person: 更新后的人员信息
+//   - error: 错误信息
+func (p *PersonService) UpdatePerson(id int64, person *models.Person, fields []string) (*models.Person, error) {
+	existing, err := p.getActivePerson(id)
+	if err != nil {
+		return nil, err
+	}
+	person = applyPersonUpdate(existing, person, fields)
+	if err := p.fillRoomAddress(person); err != nil {
+		return nil, err
+	}
+	if err := validatePerson(person, idCardChanged(existing, person, fields)); err != nil {
+		return nil, err
+	}
+	if !p.access.CanAccessBuilding(person.BuildingNumber) {
+		return nil, errNoBuildingAccess
+	}
+	person.ID = existing.ID
+	person.IsDel = 0
+	person.CreatedAt = existing.CreatedAt
+	err = p.db.Transaction(func(tx *gorm.DB) error {
+		roomID, err := newRoomResolver(tx).resolve(person.BuildingNumber, person.UnitNumber, person.RoomNumber)
+		if err != nil {
+			return err
+		}
+		person.RoomID = roomID
+		// 是否有电动车、车牌号、品牌型号由电动车表同步维护，不随人员信息更新
+		if err := tx.Omit("has_electric_car", "license_plate", "brand_model").Save(person).Error; err != nil {
+			return errors.New("更新人员失败: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.GetPerson(id)
+}
+
+// applyPersonUpdate 在已有人员信息的副本上应用请求中包含的字段，敏感字段为脱敏值时保留原值
+func applyPersonUpdate(existing, incoming *models.Person, fields []string) *models.Person {
+	person := &models.Person{}
+	*person = *existing
+	person.ApplyFields(incoming, fields)
+	// 修改了楼号、单元或房号但没有指定房间时，按新的地址重新关联房间
+	if !slices.Contains(fields, "room_id") && slices.ContainsFunc(fields, func(field string) bool {
+		return field == "building_number" || field == "unit_number" || field == "room_number"
+	}) {
+		person.RoomID = nil
+	}
+	person.KeepMaskedFields(existing)
+	return person
+}
+
+// idCardChanged 更新时是否修改了身份证号
+// 只有修改身份证号时才严格校验，已有的号码（如导入的校验位错误的号码）不影响更新其他字段
+func idCardChanged(existing, person *models.Person, fields []string) bool {
+	return slices.Contains(fields, "id_card") && strings.TrimSpace(person.IDCard) != strings.TrimSpace(existing.IDCard)
+}
+
+// DeletePerson 删除人员（软删除，设置 is_del = 1），名下的电动车同时软删除
+func (p *PersonService) DeletePerson(id int64) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		result := p.access.scopeQuery(tx.Model(&models.Person{})).Where("id = ? AND is_del = 0", id).Update("is_del", 1)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("人员不存在")
+		}
+		return tx.Model(&models.ElectricBicycle{}).Where("person_id = ? AND is_del = 0", id).Update("is_del", 1).Error
+	})
+}
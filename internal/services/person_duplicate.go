@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 重复原因
+const (
+	DuplicateByIDCard    = "id_card"    // 身份证号相同
+	DuplicateByNamePhone = "name_phone" // 姓名、电话相同
+	DuplicateByNameRoom  = "name_room"  // 姓名、房间相同
+)
+
+// duplicateRules 各重复原因的分组字段（按优先级排列，同一组人员只按第一个匹配的原因列出）
+var duplicateRules = []struct {
+	Reason  string
+	Where   string
+	Columns []string
+}{
+	{DuplicateByIDCard, "id_card_bidx <> ''", []string{"id_card_bidx"}},
+	{DuplicateByNamePhone, "name <> '' AND telephone_bidx <> ''", []string{"name", "telephone_bidx"}},
+	{DuplicateByNameRoom, "name <> ''", []string{"building_number", "unit_number", "room_number", "name"}},
+}
+
+// DuplicateQuery 重复人员查询条件
+type DuplicateQuery struct {
+	Reason         string `form:"reason"`         // 重复原因，为空表示全部
+	BuildingNumber string `form:"buildingNumber"` // 楼号，为空表示全部
+	Page           int    `form:"page"`
+	PageSize       int    `form:"pageSize"`
+}
+
+// DuplicateGroup 一组疑似重复的人员
+type DuplicateGroup struct {
+	Reason  string          `json:"reason"`
+	Persons []models.Person `json:"persons"`
+}
+
+// FindDuplicates 分页查询疑似重复的人员（身份证号相同、姓名+电话相同或姓名+房间相同）
+func (p *PersonService) FindDuplicates(query *DuplicateQuery) ([]DuplicateGroup, int64, error) {
+	cond, params := p.access.scopeSQL()
+	if query.BuildingNumber != "" {
+		if !p.access.CanAccessBuilding(query.BuildingNumber) {
+			return nil, 0, errNoBuildingAccess
+		}
+		cond += " AND building_number = ?"
+		params = append(params, query.BuildingNumber)
+	}
+
+	type group struct {
+		reason string
+		ids    []int64
+	}
+	var groups []group
+	seen := make(map[string]struct{})
+	for _, rule := range duplicateRules {
+		if query.Reason != "" && query.Reason != rule.Reason {
+			continue
+		}
+		// 按分组字段关联重复的分组读取各组人员ID，组以最小的人员ID标识
+		// （不使用 GROUP_CONCAT，其结果有长度限制，ID 较多时会被截断）
+		columns := strings.Join(rule.Columns, ", ")
+		filtered := "FROM person WHERE is_del = 0 AND " + rule.Where + cond
+		on := make([]string, 0, len(rule.Columns))
+		for _, column := range rule.Columns {
+			on = append(on, "p."+column+" = d."+column)
+		}
+		var rows []struct {
+			ID      int64
+			GroupID int64
+		}
+		sql := "SELECT p.id, d.group_id FROM (SELECT id, " + columns + " " + filtered + ") p" +
+			" JOIN (SELECT MIN(id) AS group_id, " + columns + " " + filtered +
+			" GROUP BY " + columns + " HAVING COUNT(*) > 1) d ON " + strings.Join(on, " AND ") +
+			" ORDER BY d.group_id, p.id"
+		if err := p.db.Raw(sql, append(append([]interface{}{}, params...), params...)...).Scan(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+
+		var ruleGroups []group
+		for i, row := range rows {
+			if i == 0 || row.GroupID != rows[i-1].GroupID {
+				ruleGroups = append(ruleGroups, group{reason: rule.Reason})
+			}
+			last := &ruleGroups[len(ruleGroups)-1]
+			last.ids = append(last.ids, row.ID)
+		}
+		for _, g := range ruleGroups {
+			key := fmt.Sprint(g.ids)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			groups = append(groups, g)
+		}
+	}
+
+	total := int64(len(groups))
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(groups) {
+		return []DuplicateGroup{}, total, nil
+	}
+	end := start + query.PageSize
+	if end > len(groups) {
+		end = len(groups)
+	}
+	groups = groups[start:end]
+
+	var ids []int64
+	for _, g := range groups {
+		ids = append(ids, g.ids...)
+	}
+	var persons []models.Person
+	if err := p.db.Where("id IN ?", ids).Find(&persons).Error; err != nil {
+		return nil, 0, err
+	}
+	p.maskPersons(persons)
+	byID := make(map[int64]models.Person, len(persons))
+	for _, person := range persons {
+		byID[person.ID] = person
+	}
+
+	result := make([]DuplicateGroup, 0, len(groups))
+	for _, g := range groups {
+		dg := DuplicateGroup{Reason: g.reason, Persons: make([]models.Person, 0, len(g.ids))}
+		for _, id := range g.ids {
+			if person, ok := byID[id]; ok {
+				dg.Persons = append(dg.Persons, person)
+			}
+		}
+		result = append(result, dg)
+	}
+	return result, total, nil
+}
+
+// MergePersonsRequest 合并重复人员请求
+type MergePersonsRequest struct {
+	TargetID  int64   `json:"targetId" binding:"required"`        // 保留的人员ID
+	SourceIDs []int64 `json:"sourceIds" binding:"required,min=1"` // 被合并的人员ID
+	Reason    string  `json:"reason"`                             // 重复原因（记录在合并记录中）
+}
+
+// MergeResult 合并结果
+type MergeResult struct {
+	Person        *models.Person `json:"person"`        // 合并后的人员
+	MergedIDs     []int64        `json:"mergedIds"`     // 已合并（软删除）的人员ID
+	FilledFields  []string       `json:"filledFields"`  // 从被合并人员补充的字段
+	BicyclesMoved int64          `json:"bicyclesMoved"` // 转移的电动车数
+}
+
+// MergePersons 合并重复人员：保留的人员为空的字段用被合并人员的值补充，
+// 被合并人员的电动车转移到保留的人员，被合并人员软删除，每个被合并人员记录一条合并记录
+func (p *PersonService) MergePersons(req *MergePersonsRequest, operator string) (*MergeResult, error) {
+	switch req.Reason {
+	case "", DuplicateByIDCard, DuplicateByNamePhone, DuplicateByNameRoom:
+	default:
+		return nil, errors.New("重复原因取值错误：id_card、name_phone、name_room")
+	}
+	sourceIDs := make([]int64, 0, len(req.SourceIDs))
+	seen := make(map[int64]struct{})
+	for _, id := range req.SourceIDs {
+		if id == req.TargetID {
+			return nil, errors.New("被合并的人员不能包含保留的人员")
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+
+	result := &MergeResult{MergedIDs: sourceIDs, FilledFields: []string{}}
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		var target models.Person
+		if err := p.access.scopeQuery(tx).Where("id = ? AND is_del = 0", req.TargetID).First(&target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("保留的人员不存在")
+			}
+			return err
+		}
+		var sources []models.Person
+		if err := p.access.scopeQuery(tx).Where("id IN ? AND is_del = 0", sourceIDs).Order("id").Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(sourceIDs) {
+			return errors.New("被合并的人员不存在或已删除")
+		}
+
+		// 保留的人员为空的字段用被合并人员的值补充（按人员ID顺序取第一个非空值）
+		values := personImportValues(&target)
+		changes := make(map[string]interface{})
+		filledBy := make(map[int64][]string)
+		for i := range sources {
+			for column, value := range personImportValues(&sources[i]) {
+				if isEmptyPersonValue(values[column]) && !isEmptyPersonValue(value) {
+					values[column] = value
+					changes[column] = value
+					filledBy[sources[i].ID] = append(filledBy[sources[i].ID], column)
+				}
+			}
+		}
+		for column := range changes {
+			result.FilledFields = append(result.FilledFields, column)
+		}
+		sort.Strings(result.FilledFields)
+		if len(changes) > 0 {
+			if err := models.EncryptPersonValues(changes); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Person{}).Where("id = ?", target.ID).Updates(changes).Error; err != nil {
+				return err
+			}
+			// 补充了楼号、单元或房号时重新关联房间
+			if hasAddressChange(changes) {
+				if err := syncPersonRoom(tx, newRoomResolver(tx), target.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range sources {
+			source := &sources[i]
+			moved := tx.Model(&models.ElectricBicycle{}).Where("person_id = ?", source.ID).Update("person_id", target.ID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			result.BicyclesMoved += moved.RowsAffected
+
+			snapshot, err := json.Marshal(source)
+			if err != nil {
+				return err
+			}
+			sort.Strings(filledBy[source.ID])
+			if err := tx.Create(&models.PersonMerge{
+				TargetID:       target.ID,
+				SourceID:       source.ID,
+				Reason:         req.Reason,
+				Operator:       operator,
+				FilledFields:   strings.Join(filledBy[source.ID], ","),
+				BicycleNum:     moved.RowsAffected,
+				SourceSnapshot: string(snapshot),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Person{}).Where("id IN ?", sourceIDs).Update("is_del", 1).Error; err != nil {
+			return err
+		}
+		if result.BicyclesMoved == 0 {
+			return nil
+		}
+		return syncPersonElectricCar(tx, target.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Person, err = p.GetPerson(req.TargetID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isEmptyPersonValue 人员字段值是否为空（空字符串、0 或 nil）
+func isEmptyPersonValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case int:
+		return v == 0
+	}
+	return false
+}
+
+// PersonMergeQuery 合并记录查询条件
+type PersonMergeQuery struct {
+	PersonID int64 `form:"personId"` // 保留或被合并的人员ID，为 0 表示全部
+	Page     int   `form:"page"`
+	PageSize int   `form:"pageSize"`
+}
+
+// GetPersonMerges 分页查询合并记录（按时间倒序）
+func (p *PersonService) GetPersonMerges(query *PersonMergeQuery) ([]models.PersonMerge, int64, error) {
+	filtered := func() *gorm.DB {
+		db := p.db.Model(&models.PersonMerge{})
+		if query.PersonID > 0 {
+			db = db.Where("target_person_id = ? OR source_person_id = ?", query.PersonID, query.PersonID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var merges []models.PersonMerge
+	offset := (query.Page - 1) * query.PageSize
+	if err := filtered().Order("id DESC").Offset(offset).Limit(query.PageSize).Find(&merges).Error; err != nil {
+		return nil, 0, err
+	}
+	return merges, total, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"testing"
+
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestUpdatePersonWithInvalidIDCard(t *testing.T) {
+	// 导入的身份证号校验位错误
+	const invalidIDCard = "110105194912310021"
+	existing := &models.Person{
+		ID:             1,
+		BuildingNumber: "1",
+		UnitNumber:     1,
+		RoomNumber:     "101",
+		Name:           "张三",
+		IDCard:         invalidIDCard,
+		Telephone:      "13812345678",
+		Age:            70,
+	}
+	tests := []struct {
+		name       string
+		incoming   models.Person
+		fields     []string
+		wantErr    bool
+		wantIDCard string
+		wantGender int
+	}{
+		{
+			name:       "只修改电话",
+			incoming:   models.Person{Telephone: "13987654321"},
+			fields:     []string{"telephone"},
+			wantIDCard: invalidIDCard,
+		},
+		{
+			name:       "回传脱敏的身份证号",
+			incoming:   models.Person{IDCard: models.MaskIDCard(invalidIDCard), Telephone: "13987654321"},
+			fields:     []string{"id_card", "telephone"},
+			wantIDCard: invalidIDCard,
+		},
+		{
+			name:       "回传相同的身份证号",
+			incoming:   models.Person{IDCard: invalidIDCard, Name: "张三丰"},
+			fields:     []string{"id_card", "name"},
+			wantIDCard: invalidIDCard,
+		},
+		{
+			name:       "更正为有效的身份证号",
+			incoming:   models.Person{IDCard: "11010519491231002x"},
+			fields:     []string{"id_card"},
+			wantIDCard: "11010519491231002X",
+			wantGender: 2,
+		},
+		{
+			name:     "改为另一个无效的身份证号",
+			incoming: models.Person{IDCard: "110105194912310022"},
+			fields:   []string{"id_card"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			incoming := tt.incoming
+			person := applyPersonUpdate(existing, &incoming, tt.fields)
+			err := validatePerson(person, idCardChanged(existing, person, tt.fields))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("应返回身份证号错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validatePerson 返回错误: %v", err)
+			}
+			if person.IDCard != tt.wantIDCard {
+				t.Errorf("IDCard = %q, want %q", person.IDCard, tt.wantIDCard)
+			}
+			if person.Gender != tt.wantGender {
+				t.Errorf("Gender = %d, want %d", person.Gender, tt.wantGender)
+			}
+			for _, field := range tt.fields {
+				if field == "telephone" && person.Telephone != incoming.Telephone {
+					t.Errorf("Telephone = %q, want %q", person.Telephone, incoming.Telephone)
+				}
+			}
+		})
+	}
+	if existing.Telephone != "13812345678" {
+		t.Error("不应修改已有人员信息")
+	}
+}
+
+func TestSensitiveCondition(t *testing.T) {
+	if err := fieldcrypt.Configure(fieldcrypt.Config{AllowDevKey: true}); err != nil {
+		t.Fatal(err)
+	}
+	expr, ok := sensitiveCondition("id_card", "11010519491231002X").(clause.OrConditions)
+	if !ok || len(expr.Exprs) != 2 {
+		t.Fatalf("sensitiveCondition 应返回两个条件的 OR，实际为 %#v", expr)
+	}
+	want := []clause.Eq{
+		{Column: clause.Column{Name: "id_card_bidx"}, Value: fieldcrypt.BlindIndex("11010519491231002X")},
+		{Column: clause.Column{Name: "id_card"}, Value: "11010519491231002X"},
+	}
+	for i, w := range want {
+		if got, ok := expr.Exprs[i].(clause.Eq); !ok || got != w {
+			t.Errorf("条件 %d = %#v, want %#v", i, expr.Exprs[i], w)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"PLMS/internal/idcard"
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 数据质量问题
+const (
+	QualityIDCardMissing       = "id_card_missing"      // 缺少身份证号
+	QualityIDCardInvalid       = "id_card_invalid"      // 身份证号无效
+	QualityGenderUnknown       = "gender_unknown"       // 性别未知
+	QualityPermanentUnknown    = "permanent_unknown"    // 是否常住未知
+	QualityTelephoneMissing    = "telephone_missing"    // 缺少联系方式
+	QualityHousingInconsistent = "housing_inconsistent" // 同一房间住户的住房情况不一致（按房间统计）
+	QualityContactStale        = "contact_stale"        // 超过指定天数未联系（或无联系记录）
+)
+
+// defaultStaleDays 默认超过多少天未联系视为联系过期
+const defaultStaleDays = 90
+
+// qualityPersonIssues 按人员统计的问题
+var qualityPersonIssues = []string{
+	QualityIDCardMissing,
+	QualityIDCardInvalid,
+	QualityGenderUnknown,
+	QualityPermanentUnknown,
+	QualityTelephoneMissing,
+	QualityContactStale,
+}
+
+// IsQualityIssue 是否为支持的数据质量问题
+func IsQualityIssue(issue string) bool {
+	if issue == QualityHousingInconsistent {
+		return true
+	}
+	for _, i := range qualityPersonIssues {
+		if i == issue {
+			return true
+		}
+	}
+	return false
+}
+
+// contactDatePattern 最后联系时间中的日期（如 2024-05-01、2024/5/1、2024年5月1日）
+var contactDatePattern = regexp.MustCompile(`(\d{4})\s*[-/.年]\s*(\d{1,2})\s*[-/.月]\s*(\d{1,2})`)
+
+// QualityService 数据质量检查服务
+type QualityService struct {
+	db     *gorm.DB
+	access *UserAccess // 当前用户的权限范围，nil 表示不限制
+}
+
+// NewQualityService 创建数据质量检查服务实例
+func NewQualityService(db *gorm.DB) *QualityService {
+	return &QualityService{db: db}
+}
+
+// WithAccess 返回按指定用户权限范围检查的服务
+func (s *QualityService) WithAccess(access *UserAccess) *QualityService {
+	return &QualityService{db: s.db, access: access}
+}
+
+// QualityQuery 数据质量查询条件
+type QualityQuery struct {
+	BuildingNumber string `form:"buildingNumber"` // 楼号，为空表示全部
+	StaleDays      int    `form:"staleDays"`      // 超过多少天未联系视为联系过期，默认 90
+	Page           int    `form:"page"`
+	PageSize       int    `form:"pageSize"`
+}
+
+// BuildingQuality 单个楼栋的数据质量统计
+type BuildingQuality struct {
+	BuildingNumber      string `json:"building_number"`
+	TotalPersons        int    `json:"total_persons"`        // 人员数
+	IDCardMissing       int    `json:"id_card_missing"`      // 缺少身份证号
+	IDCardInvalid       int    `json:"id_card_invalid"`      // 身份证号无效
+	GenderUnknown       int    `json:"gender_unknown"`       // 性别未知
+	PermanentUnknown    int    `json:"permanent_unknown"`    // 是否常住未知
+	TelephoneMissing    int    `json:"telephone_missing"`    // 缺少联系方式
+	HousingInconsistent int    `json:"housing_inconsistent"` // 住房情况不一致的房间数
+	ContactStale        int    `json:"contact_stale"`        // 联系过期
+}
+
+// add 累加问题数
+func (b *BuildingQuality) add(issue string, n int) {
+	switch issue {
+	case QualityIDCardMissing:
+		b.IDCardMissing += n
+	case QualityIDCardInvalid:
+		b.IDCardInvalid += n
+	case QualityGenderUnknown:
+		b.GenderUnknown += n
+	case QualityPermanentUnknown:
+		b.PermanentUnknown += n
+	case QualityTelephoneMissing:
+		b.TelephoneMissing += n
+	case QualityHousingInconsistent:
+		b.HousingInconsistent += n
+	case QualityContactStale:
+		b.ContactStale += n
+	}
+}
+
+// QualityReport 数据质量报告
+type QualityReport struct {
+	StaleDays int               `json:"stale_days"` // 联系过期天数
+	Total     BuildingQuality   `json:"total"`      // 合计
+	Buildings []BuildingQuality `json:"buildings"`  // 各楼栋统计
+}
+
+// QualityPerson 存在数据质量问题的人员
+type QualityPerson struct {
+	models.Person
+	Detail string `json:"detail"` // 问题说明
+}
+
+// InconsistentRoom 住房情况不一致的房间
+type InconsistentRoom struct {
+	BuildingNumber string          `json:"building_number"`
+	UnitNumber     int             `json:"unit_number"`
+	RoomNumber     string          `json:"room_number"`
+	Situations     []string        `json:"situations"` // 各住户填写的住房情况
+	Persons        []models.Person `json:"persons"`
+}
+
+// qualityChecker 单次检查使用的参数
+type qualityChecker struct {
+	now         time.Time
+	staleBefore time.Time
+}
+
+func newQualityChecker(staleDays int) *qualityChecker {
+	now := time.Now()
+	return &qualityChecker{now: now, staleBefore: now.AddDate(0, 0, -staleDays)}
+}
+
+// check 检查人员是否存在指定问题，返回问题说明
+func (q *qualityChecker) check(person *models.Person, issue string) (string, bool) {
+	switch issue {
+	case QualityIDCardMissing:
+		return "缺少身份证号", person.IDCard == ""
+	case QualityIDCardInvalid:
+		if person.IDCard == "" {
+			return "", false
+		}
+		if _, err := idcard.Parse(person.IDCard); err != nil {
+			return err.Error(), true
+		}
+	case QualityGenderUnknown:
+		return "性别未知", person.Gender == 0
+	case QualityPermanentUnknown:
+		return "是否常住未知", person.IsPermanent == 0
+	case QualityTelephoneMissing:
+		return "缺少联系方式", person.Telephone == ""
+	case QualityContactStale:
+		last, ok := lastContactDate(person.LastContactTime)
+		if !ok {
+			return "无联系记录", true
+		}
+		if last.Before(q.staleBefore) {
+			return "最后联系 " + last.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// lastContactDate 解析最后联系时间中最近的日期（可能记录了多次联系）
+func lastContactDate(value string) (time.Time, bool) {
+	var last time.Time
+	for _, m := range contactDatePattern.FindAllStringSubmatch(value, -1) {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			continue
+		}
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// normalize 校验楼号范围并设置默认的联系过期天数
+func (s *QualityService) normalize(query *QualityQuery) error {
+	if query.BuildingNumber != "" && !s.access.CanAccessBuilding(query.BuildingNumber) {
+		return errNoBuildingAccess
+	}
+	if query.StaleDays <= 0 {
+		query.StaleDays = defaultStaleDays
+	}
+	return nil
+}
+
+// activePersons 楼栋范围内未删除的人员
+func (s *QualityService) activePersons(query *QualityQuery) *gorm.DB {
+	db := s.access.scopeQuery(s.db.Model(&models.Person{})).Where("is_del = 0")
+	if query.BuildingNumber != "" {
+		db = db.Where("building_number = ?", query.BuildingNumber)
+	}
+	return db
+}
+
+// loadCheckPersons 加载检查所需的人员字段
+// 身份证号、电话加密存储，无法在数据库中校验，读出后逐个检查
+func (s *QualityService) loadCheckPersons(query *QualityQuery) ([]models.Person, error) {
+	var persons []models.Person
+	err := s.activePersons(query).
+		Select("id, building_number, unit_number, room_number, id_card, gender, is_permanent, telephone, last_contact_time").
+		Order("building_number, unit_number, CAST(room_number AS UNSIGNED), room_number, id").
+		Find(&persons).Error
+	return persons, err
+}
+
+// inconsistentRoomsQuery 同一房间住户填写了不同住房情况的房间（空值不参与比较）
+func (s *QualityService) inconsistentRoomsQuery(query *QualityQuery) *gorm.DB {
+	return s.activePersons(query).
+		Select("building_number, unit_number, room_number, GROUP_CONCAT(DISTINCT NULLIF(housing_situation, '') SEPARATOR '\\n') AS situations").
+		Group("building_number, unit_number, room_number").
+		Having("COUNT(DISTINCT NULLIF(housing_situation, '')) > 1")
+}
+
+// GetReport 按楼栋统计数据质量问题
+func (s *QualityService) GetReport(query *QualityQuery) (*QualityReport, error) {
+	if err := s.normalize(query); err != nil {
+		return nil, err
+	}
+	persons, err := s.loadCheckPersons(query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QualityReport{StaleDays: query.StaleDays, Total: BuildingQuality{}, Buildings: []BuildingQuality{}}
+	index := make(map[string]int)
+	building := func(number string) *BuildingQuality {
+		i, ok := index[number]
+		if !ok {
+			i = len(report.Buildings)
+			index[number] = i
+			report.Buildings = append(report.Buildings, BuildingQuality{BuildingNumber: number})
+		}
+		return &report.Buildings[i]
+	}
+
+	checker := newQualityChecker(query.StaleDays)
+	for i := range persons {
+		person := &persons[i]
+		b := building(person.BuildingNumber)
+		b.TotalPersons++
+		for _, issue := range qualityPersonIssues {
+			if _, ok := checker.check(person, issue); ok {
+				b.add(issue, 1)
+			}
+		}
+	}
+
+	var rooms []struct {
+		BuildingNumber string
+		Count          int
+	}
+	if err := s.db.Table("(?) AS rooms", s.inconsistentRoomsQuery(query)).
+		Select("building_number, COUNT(*) AS count").
+		Group("building_number").
+		Scan(&rooms).Error; err != nil {
+		return nil, err
+	}
+	for _, room := range rooms {
+		building(room.BuildingNumber).add(QualityHousingInconsistent, room.Count)
+	}
+
+	for _, b := range report.Buildings {
+		report.Total.TotalPersons += b.TotalPersons
+		report.Total.IDCardMissing += b.IDCardMissing
+		report.Total.IDCardInvalid += b.IDCardInvalid
+		report.Total.GenderUnknown += b.GenderUnknown
+		report.Total.PermanentUnknown += b.PermanentUnknown
+		report.Total.TelephoneMissing += b.TelephoneMissing
+		report.Total.HousingInconsistent += b.HousingInconsistent
+		report.Total.ContactStale += b.ContactStale
+	}
+	return report, nil
+}
+
+// GetIssuePersons 分页获取存在指定问题的人员（无查看敏感信息权限时敏感字段脱敏）
+func (s *QualityService) GetIssuePersons(issue string, query *QualityQuery) ([]QualityPerson, int64, error) {
+	if issue == QualityHousingInconsistent || !IsQualityIssue(issue) {
+		return nil, 0, errors.New("不支持的数据质量问题")
+	}
+	if err := s.normalize(query); err != nil {
+		return nil, 0, err
+	}
+	persons, err := s.loadCheckPersons(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	checker := newQualityChecker(query.StaleDays)
+	var ids []int64
+	details := make(map[int64]string)
+	for i := range persons {
+		if detail, ok := checker.check(&persons[i], issue); ok {
+			ids = append(ids, persons[i].ID)
+			details[persons[i].ID] = detail
+		}
+	}
+
+	total := int64(len(ids))
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(ids) {
+		return []QualityPerson{}, total, nil
+	}
+	end := start + query.PageSize
+	if end > len(ids) {
+		end = len(ids)
+	}
+	ids = ids[start:end]
+
+	var full []models.Person
+	if err := s.db.Where("id IN ?", ids).Find(&full).Error; err != nil {
+		return nil, 0, err
+	}
+	if !s.access.HasPermission(models.PermViewSensitive) {
+		for i := range full {
+			full[i].MaskSensitive()
+		}
+	}
+	byID := make(map[int64]models.Person, len(full))
+	for _, person := range full {
+		byID[person.ID] = person
+	}
+
+	result := make([]QualityPerson, 0, len(ids))
+	for _, id := range ids {
+		if person, ok := byID[id]; ok {
+			result = append(result, QualityPerson{Person: person, Detail: details[id]})
+		}
+	}
+	return result, total, nil
+}
+
+// GetInconsistentRooms 分页获取住房情况不一致的房间及住户
+func (s *QualityService) GetInconsistentRooms(query *QualityQuery) ([]InconsistentRoom, int64, error) {
+	if err := s.normalize(query); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := s.db.Table("(?) AS rooms", s.inconsistentRoomsQuery(query)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		BuildingNumber string
+		UnitNumber     int
+		RoomNumber     string
+		Situations     string
+	}
+	offset := (query.Page - 1) * query.PageSize
+	if err := s.inconsistentRoomsQuery(query).
+		Order("building_number, unit_number, CAST(room_number AS UNSIGNED), room_number").
+		Offset(offset).Limit(query.PageSize).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	rooms := make([]InconsistentRoom, 0, len(rows))
+	for _, row := range rows {
+		situations := strings.Split(row.Situations, "\n")
+		sort.Strings(situations)
+		var persons []models.Person
+		if err := s.activePersons(query).
+			Where("building_number = ? AND unit_number = ? AND room_number = ?", row.BuildingNumber, row.UnitNumber, row.RoomNumber).
+			Order("id").Find(&persons).Error; err != nil {
+			return nil, 0, err
+		}
+		if !s.access.HasPermission(models.PermViewSensitive) {
+			for i := range persons {
+				persons[i].MaskSensitive()
+			}
+		}
+		rooms = append(rooms, InconsistentRoom{
+			BuildingNumber: row.BuildingNumber,
+			UnitNumber:     row.UnitNumber,
+			RoomNumber:     row.RoomNumber,
+			Situations:     situations,
+			Persons:        persons,
+		})
+	}
+	return rooms, total, nil
+}
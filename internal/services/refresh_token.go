@@ -0,0 +1,123 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRequest 刷新token请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// errInvalidRefreshToken 刷新token无效（不存在、已过期或已吊销）
+var errInvalidRefreshToken = errors.New("刷新token无效或已过期，请重新登录")
+
+// hashRefreshToken 计算刷新token的SHA-256（数据库只保存哈希）
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens 为用户签发访问token及新的刷新token，并记录登录会话
+// 参数:
+//   - user: 用户
+//   - familyID: 登录会话ID，同一次登录轮换出的刷新token相同
+//   - client: 客户端信息
+//
+// 返回值:
+//   - *LoginResponse: 访问token、刷新token及用户信息
+//   - error: 错误信息
+func (s *AuthService) issueTokens(user *models.SysUser, familyID string, client ClientInfo) (*LoginResponse, error) {
+	accessToken, err := GenerateToken(user.ID, user.Username, user.Role, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	record := &models.SysRefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashRefreshToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(authConfig.RefreshTokenTTL),
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return nil, errors.New("保存刷新token失败: " + err.Error())
+	}
+	if err := s.saveSession(user.ID, familyID, client, record.ExpiresAt); err != nil {
+		return nil, errors.New("保存登录会话失败: " + err.Error())
+	}
+
+	return &LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(authConfig.AccessTokenTTL / time.Second),
+		User:         user.ToUserInfo(),
+	}, nil
+}
+
+// revokeRefreshFamily 吊销同一登录会话的全部刷新token，并将会话标记为已吊销
+func (s *AuthService) revokeRefreshFamily(tx *gorm.DB, familyID string) error {
+	now := time.Now()
+	if err := tx.Model(&models.SysRefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.SysUserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// RefreshToken 使用刷新token换取新的访问token和刷新token（旧刷新token作废）
+// 已使用过的刷新token再次使用视为泄露：吊销该会话及用户的全部token
+func (s *AuthService) RefreshToken(req *RefreshTokenRequest, client ClientInfo) (*LoginResponse, error) {
+	var record models.SysRefreshToken
+	if err := s.db.Where("token_hash = ?", hashRefreshToken(req.RefreshToken)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, errInvalidRefreshToken
+	}
+
+	// 标记为已使用；并发请求中只有一个能成功
+	result := s.db.Model(&models.SysRefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("检测到刷新token重复使用, userID: %d, familyID: %s", record.UserID, record.FamilyID)
+		if err := s.revokeRefreshFamily(s.db, record.FamilyID); err != nil {
+			return nil, err
+		}
+		if err := s.RevokeUserTokens(record.UserID, models.RevokeReasonRefreshReused); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefreshToken
+	}
+
+	var user models.SysUser
+	if err := s.db.Where("id = ? AND status = 1", record.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(&user, record.FamilyID, client)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval 会话最后活跃时间的最小更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// ClientInfo 发起登录或请求的客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionView 登录会话（附用户名及是否为当前会话）
+type SessionView struct {
+	models.SysUserSession
+	Username string `json:"username"`
+	Current  bool   `json:"current"`
+}
+
+// sessionTouches 各会话最近一次写入最后活跃时间的时间
+var sessionTouches = struct {
+	sync.Mutex
+	touched  map[string]time.Time
+	prunedAt time.Time
+}{touched: make(map[string]time.Time)}
+
+// saveSession 登录时创建会话，刷新token时更新会话的最后活跃时间及过期时间
+func (s *AuthService) saveSession(userID int64, sessionID string, client ClientInfo, expiresAt time.Time) error {
+	now := time.Now()
+	var session models.SysUserSession
+	err := s.db.Where("session_id = ?", sessionID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.db.Create(&models.SysUserSession{
+			SessionID:  sessionID,
+			UserID:     userID,
+			IP:         client.IP,
+			UserAgent:  truncateString(client.UserAgent, 512),
+			LastSeenAt: now,
+			LastSeenIP: client.IP,
+			ExpiresAt:  expiresAt,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&session).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"last_seen_ip": client.IP,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// TouchSession 更新会话的最后活跃时间（同一会话每分钟最多写一次数据库）
+func (s *AuthService) TouchSession(sessionID, ip string) {
+	if sessionID == "" {
+		return
+	}
+	now := time.Now()
+
+	sessionTouches.Lock()
+	if now.Sub(sessionTouches.touched[sessionID]) < sessionTouchInterval {
+		sessionTouches.Unlock()
+		return
+	}
+	sessionTouches.touched[sessionID] = now
+	if now.Sub(sessionTouches.prunedAt) > 10*sessionTouchInterval {
+		for id, t := range sessionTouches.touched {
+			if now.Sub(t) >= sessionTouchInterval {
+				delete(sessionTouches.touched, id)
+			}
+		}
+		sessionTouches.prunedAt = now
+	}
+	sessionTouches.Unlock()
+
+	if err := s.db.Model(&models.SysUserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"last_seen_ip": ip,
+		}).Error; err != nil {
+		log.Printf("更新会话活跃时间失败: %v", err)
+	}
+}
+
+// activeSessions 未吊销且未过期的会话
+func (s *AuthService) activeSessions() *gorm.DB {
+	return s.db.Table("sys_user_session AS s").
+		Joins("LEFT JOIN sys_user u ON u.id = s.user_id").
+		Where("s.revoked_at IS NULL AND s.expires_at > ?", time.Now())
+}
+
+// GetUserSessions 获取用户当前有效的登录会话
+// 参数:
+//   - userID: 用户ID
+//   - currentSessionID: 当前请求所属的会话ID，用于标记当前会话
+func (s *AuthService) GetUserSessions(userID int64, currentSessionID string) ([]SessionView, error) {
+	var sessions []SessionView
+	if err := s.activeSessions().
+		Select("s.*, u.username").
+		Where("s.user_id = ?", userID).
+		Order("s.last_seen_at DESC").
+		Scan(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// SessionQuery 会话列表查询条件（管理员接口）
+type SessionQuery struct {
+	UserID   int64  // 为 0 表示全部用户
+	Username string // 用户名模糊匹配
+	Page     int
+	PageSize int
+}
+
+// GetSessionList 分页获取全部用户的有效登录会话（管理员接口）
+func (s *AuthService) GetSessionList(query *SessionQuery) ([]SessionView, int64, error) {
+	filtered := func() *gorm.DB {
+		db := s.activeSessions()
+		if query.UserID > 0 {
+			db = db.Where("s.user_id = ?", query.UserID)
+		}
+		if query.Username != "" {
+			db = db.Where("u.username LIKE ?", "%"+query.Username+"%")
+		}
+		return db
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []SessionView
+	offset := (query.Page - 1) * query.PageSize
+	if err := filtered().Select("s.*, u.username").
+		Order("s.last_seen_at DESC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Scan(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// RevokeSession 吊销登录会话：刷新token失效，该会话已签发的访问token同时失效
+// 参数:
+//   - userID: 会话所属用户，为 0 时不限制（管理员接口）
+//   - id: 会话记录ID
+func (s *AuthService) RevokeSession(userID, id int64) error {
+	var session models.SysUserSession
+	db := s.db.Where("id = ?", id)
+	if userID > 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if err := db.First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("会话不存在")
+		}
+		return err
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return errors.New("会话已失效")
+	}
+
+	if err := s.revokeRefreshFamily(s.db, session.SessionID); err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.saveRevocation(&models.SysTokenRevocation{
+		SessionID: session.SessionID,
+		UserID:    session.UserID,
+		Reason:    models.RevokeReasonSessionRevoked,
+		RevokedAt: now,
+		ExpiresAt: now.Add(authConfig.AccessTokenTTL),
+	})
+}
+
+// truncateString 按字符截断字符串
+func truncateString(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"PLMS/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// revocationRefreshInterval 吊销缓存从数据库刷新的间隔（多实例部署时其他实例的吊销在此间隔内生效）
+const revocationRefreshInterval = 30 * time.Second
+
+// revocationCache 吊销记录的内存缓存，数据库为准
+type revocationCache struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // token ID -> 记录失效时间
+	sessions map[string]time.Time // 会话ID -> 记录失效时间
+	users    map[int64]time.Time  // 用户ID -> 最近一次吊销全部token的时间
+	loadedAt time.Time
+}
+
+var tokenRevocations = &revocationCache{
+	tokens:   make(map[string]time.Time),
+	sessions: make(map[string]time.Time),
+	users:    make(map[int64]time.Time),
+}
+
+// reload 从数据库加载未失效的吊销记录，并清理已失效的记录
+func (c *revocationCache) reload(db *gorm.DB) error {
+	now := time.Now()
+	var records []models.SysTokenRevocation
+	if err := db.Where("expires_at > ?", now).Find(&records).Error; err != nil {
+		return err
+	}
+	if err := db.Where("expires_at <= ?", now).Delete(&models.SysTokenRevocation{}).Error; err != nil {
+		log.Printf("清理过期token吊销记录失败: %v", err)
+	}
+
+	tokens := make(map[string]time.Time)
+	sessions := make(map[string]time.Time)
+	users := make(map[int64]time.Time)
+	for _, r := range records {
+		if r.TokenID != "" {
+			tokens[r.TokenID] = r.ExpiresAt
+		} else if r.SessionID != "" {
+			sessions[r.SessionID] = r.ExpiresAt
+		} else if r.RevokedAt.After(users[r.UserID]) {
+			users[r.UserID] = r.RevokedAt
+		}
+	}
+
+	c.mu.Lock()
+	c.tokens = tokens
+	c.sessions = sessions
+	c.users = users
+	c.loadedAt = now
+	c.mu.Unlock()
+	return nil
+}
+
+// add 将新的吊销记录加入缓存
+func (c *revocationCache) add(record *models.SysTokenRevocation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if record.TokenID != "" {
+		c.tokens[record.TokenID] = record.ExpiresAt
+	} else if record.SessionID != "" {
+		c.sessions[record.SessionID] = record.ExpiresAt
+	} else if record.RevokedAt.After(c.users[record.UserID]) {
+		c.users[record.UserID] = record.RevokedAt
+	}
+}
+
+// isRevoked 检查 token 是否已被吊销
+func (c *revocationCache) isRevoked(claims *CustomClaims) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if claims.ID != "" {
+		if _, ok := c.tokens[claims.ID]; ok {
+			return true
+		}
+	}
+	if claims.SessionID != "" {
+		if _, ok := c.sessions[claims.SessionID]; ok {
+			return true
+		}
+	}
+	if revokedAt, ok := c.users[claims.UserID]; ok {
+		// JWT 签发时间只精确到秒，吊销时间同样截断到秒比较
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revokedAt.Truncate(time.Second)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTokenRevoked 检查 token 是否已被吊销（缓存过期时先从数据库刷新）
+func (s *AuthService) IsTokenRevoked(claims *CustomClaims) (bool, error) {
+	tokenRevocations.mu.RLock()
+	stale := time.Since(tokenRevocations.loadedAt) > revocationRefreshInterval
+	tokenRevocations.mu.RUnlock()
+	if stale {
+		if err := tokenRevocations.reload(s.db); err != nil {
+			return false, err
+		}
+	}
+	return tokenRevocations.isRevoked(claims), nil
+}
+
+// saveRevocation 保存吊销记录并更新缓存
+func (s *AuthService) saveRevocation(record *models.SysTokenRevocation) error {
+	if err := s.db.Create(record).Error; err != nil {
+		return err
+	}
+	tokenRevocations.add(record)
+	return nil
+}
+
+// RevokeToken 吊销单个访问token，并吊销同一登录会话的刷新token（登出）
+func (s *AuthService) RevokeToken(claims *CustomClaims, reason string) error {
+	if claims.SessionID != "" {
+		if err := s.revokeRefreshFamily(s.db, claims.SessionID); err != nil {
+			return err
+		}
+	}
+	expiresAt := time.Now().Add(authConfig.AccessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.saveRevocation(&models.SysTokenRevocation{
+		TokenID:   claims.ID,
+		UserID:    claims.UserID,
+		Reason:    reason,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RevokeUserTokens 吊销用户当前已签发的全部访问token及刷新token（修改密码、禁用用户、强制下线）
+func (s *AuthService) RevokeUserTokens(userID int64, reason string) error {
+	now := time.Now()
+	if err := s.db.Model(&models.SysRefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.SysUserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return s.saveRevocation(&models.SysTokenRevocation{
+		UserID:    userID,
+		Reason:    reason,
+		RevokedAt: now,
+		ExpiresAt: now.Add(authConfig.AccessTokenTTL),
+	})
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 测试向量的密钥 "12345678901234567890"（Base32）
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTP(t *testing.T) {
+	// RFC 6238 附录 B 的8位验证码取后6位
+	tests := []struct {
+		name     string
+		unix     int64
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "RFC 6238 T=59", unix: 59, code: "287082", wantStep: 1, wantOK: true},
+		{name: "RFC 6238 T=1111111109", unix: 1111111109, code: "081804", wantStep: 37037036, wantOK: true},
+		{name: "RFC 6238 T=1111111111", unix: 1111111111, code: "050471", wantStep: 37037037, wantOK: true},
+		{name: "RFC 6238 T=1234567890", unix: 1234567890, code: "005924", wantStep: 41152263, wantOK: true},
+		{name: "RFC 6238 T=2000000000", unix: 2000000000, code: "279037", wantStep: 66666666, wantOK: true},
+		{name: "RFC 6238 T=20000000000", unix: 20000000000, code: "353130", wantStep: 666666666, wantOK: true},
+		{name: "前后空格", unix: 59, code: " 287082 ", wantStep: 1, wantOK: true},
+		{name: "上一个时间步", unix: 59 + totpPeriod, code: "287082", wantStep: 1, wantOK: true},
+		{name: "下一个时间步", unix: 1111111111 - totpPeriod, code: "050471", wantStep: 37037037, wantOK: true},
+		{name: "超出允许偏差", unix: 59 + 2*totpPeriod, code: "287082", wantOK: false},
+		{name: "验证码错误", unix: 59, code: "287083", wantOK: false},
+		{name: "位数错误", unix: 59, code: "94287082", wantOK: false},
+		{name: "空验证码", unix: 59, code: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := verifyTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK {
+				t.Fatalf("verifyTOTP(%q, %d) ok = %v, want %v", tt.code, tt.unix, ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("verifyTOTP(%q, %d) step = %d, want %d", tt.code, tt.unix, step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPInvalidSecret(t *testing.T) {
+	if _, ok := verifyTOTP("not-base32!", "123456", time.Unix(59, 0)); ok {
+		t.Error("密钥格式错误时应校验失败")
+	}
+}
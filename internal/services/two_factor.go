@@ -0,0 +1,318 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// tokenPurposeTwoFactor 双因素认证待验证token的用途
+	tokenPurposeTwoFactor = "2fa_pending"
+	// pendingTokenTTL 待验证token有效期
+	pendingTokenTTL = 5 * time.Minute
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+// errPendingTokenInvalid 待验证token无效或已过期
+var errPendingTokenInvalid = errors.New("验证已过期，请重新登录")
+
+// TwoFactorRequiredForRole 该角色是否必须启用双因素认证
+func TwoFactorRequiredForRole(role string) bool {
+	return authConfig.RequireAdmin2FA && role == models.RoleAdmin
+}
+
+// generatePendingToken 密码验证通过后签发的待验证token，只能用于提交验证码
+func generatePendingToken(user *models.SysUser) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	claims := CustomClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Purpose:  tokenPurposeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(pendingTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// VerifyTwoFactorRequest 提交双因素认证验证码请求
+type VerifyTwoFactorRequest struct {
+	PendingToken string `json:"pendingToken" binding:"required"`
+	Code         string `json:"code" binding:"required"` // 验证器App中的6位验证码或恢复码
+}
+
+// VerifyTwoFactorLogin 校验登录的第二步验证码，通过后签发token
+// 验证码错误与密码错误一样计入登录失败次数
+func (s *AuthService) VerifyTwoFactorLogin(req *VerifyTwoFactorRequest, client ClientInfo) (*LoginResponse, error) {
+	if ipLoginThrottle.blocked(client.IP) {
+		return nil, ErrLoginThrottled
+	}
+
+	claims, err := ParseToken(req.PendingToken)
+	if err != nil || claims.Purpose != tokenPurposeTwoFactor {
+		return nil, errPendingTokenInvalid
+	}
+	revoked, err := s.IsTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errPendingTokenInvalid
+	}
+
+	var user models.SysUser
+	if err := s.db.Where("id = ? AND status = 1", claims.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPendingTokenInvalid
+		}
+		return nil, err
+	}
+	if user.IsLocked() {
+		return nil, fmt.Errorf("%w，请于 %s 后重试", ErrAccountLocked, user.LockedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	ok, err := s.verifySecondFactor(&user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		ipLoginThrottle.fail(client.IP)
+		return nil, s.recordLoginFailure(&user, "验证码错误")
+	}
+
+	// 待验证token只能使用一次
+	if err := s.RevokeToken(claims, models.RevokeReasonTwoFactorUsed); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(&user, client)
+}
+
+// verifySecondFactor 校验 TOTP 验证码或恢复码
+// TOTP 验证码在同一时间步内只能使用一次；恢复码使用后作废
+func (s *AuthService) verifySecondFactor(user *models.SysUser, code string) (bool, error) {
+	if user.TotpEnabled != 1 || user.TotpSecret == "" {
+		return false, nil
+	}
+
+	if step, ok := verifyTOTP(user.TotpSecret, code, time.Now()); ok {
+		result := s.db.Model(&models.SysUser{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		return result.RowsAffected == 1, nil
+	}
+
+	result := s.db.Model(&models.SysRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// hashRecoveryCode 恢复码的SHA-256（忽略大小写、空格和连字符）
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes 重新生成用户的恢复码（旧的全部作废），返回明文，只展示一次
+func (s *AuthService) generateRecoveryCodes(tx *gorm.DB, userID int64) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.SysRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := encoding.EncodeToString(b)[:10]
+		code := raw[:5] + "-" + raw[5:]
+		if err := tx.Create(&models.SysRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// TwoFactorSetup 双因素认证绑定信息
+type TwoFactorSetup struct {
+	Secret string `json:"secret"` // Base32 密钥，可手动输入验证器App
+	URI    string `json:"uri"`    // otpauth:// 地址，可生成二维码扫码绑定
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥（启用前需提交验证码确认）
+func (s *AuthService) SetupTwoFactor(userID int64) (*TwoFactorSetup, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.TotpEnabled == 1 {
+		return nil, errors.New("已启用双因素认证，如需更换请先停用")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	// 按列更新不经过序列化器，需自行加密
+	encrypted, err := fieldcrypt.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("totp_secret", encrypted).Error; err != nil {
+		return nil, err
+	}
+	return &TwoFactorSetup{Secret: secret, URI: totpURI(user.Username, secret)}, nil
+}
+
+// EnableTwoFactor 校验验证码后启用双因素认证，返回恢复码
+func (s *AuthService) EnableTwoFactor(userID int64, code string) ([]string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.TotpEnabled == 1 {
+		return nil, errors.New("已启用双因素认证")
+	}
+	if user.TotpSecret == "" {
+		return nil, errors.New("请先获取绑定密钥")
+	}
+	step, ok := verifyTOTP(user.TotpSecret, code, time.Now())
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   1,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		codes, err = s.generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactorRequest 停用双因素认证请求
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactor 停用双因素认证（需验证密码和验证码）
+func (s *AuthService) DisableTwoFactor(userID int64, req *DisableTwoFactorRequest) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.TotpEnabled != 1 {
+		return errors.New("未启用双因素认证")
+	}
+	if TwoFactorRequiredForRole(user.Role) {
+		return errors.New("管理员必须启用双因素认证，不能停用")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errors.New("密码错误")
+	}
+	ok, err := s.verifySecondFactor(user, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("验证码错误")
+	}
+	return s.clearTwoFactor(userID)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（需验证码），旧恢复码作废
+func (s *AuthService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	ok, err := s.verifySecondFactor(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		codes, err = s.generateRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTwoFactor 重置用户的双因素认证（管理员接口，用于用户丢失设备）
+func (s *AuthService) ResetTwoFactor(userID int64) error {
+	if _, err := s.GetUserByID(userID); err != nil {
+		return errors.New("用户不存在")
+	}
+	return s.clearTwoFactor(userID)
+}
+
+// clearTwoFactor 清除 TOTP 密钥及恢复码
+func (s *AuthService) clearTwoFactor(userID int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   0,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.SysRecoveryCode{}).Error
+	})
+}
+
+// IsTwoFactorEnabled 用户是否已启用双因素认证
+func (s *AuthService) IsTwoFactorEnabled(userID int64) (bool, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.TotpEnabled == 1, nil
+}
@@ -1,42 +1,107 @@
 package services
 
 import (
+	"PLMS/internal/fieldcrypt"
+	"PLMS/internal/idcard"
 	"PLMS/internal/models"
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 	"regexp"
-	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ImportResult 导入结果
 type ImportResult struct {
-	TotalSheets  int      `json:"totalSheets"`  // 处理的工作表总数
-	TotalPersons int      `json:"totalPersons"` // 导入的人员总数
-	Details      []string `json:"details"`      // 详细处理信息
+	BatchID         int64               `json:"batchId"`         // 导入批次ID，可用于回滚
+	TotalSheets     int                 `json:"totalSheets"`     // 处理的工作表总数
+	TotalPersons    int                 `json:"totalPersons"`    // 导入的人员总数
+	Inserted        int                 `json:"inserted"`        // 新增人数
+	Updated         int                 `json:"updated"`         // 更新人数
+	Unchanged       int                 `json:"unchanged"`       // 未变化人数
+	Removed         int                 `json:"removed"`         // 软删除人数（表中已不存在）
+	Details         []string            `json:"details"`         // 详细处理信息
+	UnmappedHeaders map[string][]string `json:"unmappedHeaders"` // 各工作表未识别的表头
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Profile       string // 导入方案名称，为空时使用内置方案
+	RemoveMissing bool   // 是否软删除导入楼栋中表格里已不存在的人员
+	Transactional bool   // 是否整个导入在一个事务中执行（任一工作表失败则全部回滚）
+	FileName      string // 原始文件名（记录导入批次用）
+	Operator      string // 操作人（记录导入批次用）
+	OperatorID    int64  // 操作人用户ID（确认预览的导入时校验）
 }
 
 type UpdateExcelDataService struct {
-	db *gorm.DB
+	db         *gorm.DB
+	profileDir string      // 导入方案目录
+	access     *UserAccess // 当前用户权限范围，为 nil 时不限制
+}
+
+func NewUpdateExcelDataService(db *gorm.DB, profileDir string) *UpdateExcelDataService {
+	return &UpdateExcelDataService{db: db, profileDir: profileDir}
 }
 
-func NewUpdateExcelDataService(db *gorm.DB) *UpdateExcelDataService {
-	return &UpdateExcelDataService{db: db}
+// WithAccess 返回按指定用户楼栋范围导入、回滚的服务（只能导入、回滚有权访问的楼栋中的人员）
+func (s *UpdateExcelDataService) WithAccess(access *UserAccess) *UpdateExcelDataService {
+	return &UpdateExcelDataService{db: s.db, profileDir: s.profileDir, access: access}
 }
 
-func (s *UpdateExcelDataService) ImportExcelData(filePath string) (*ImportResult, error) {
-	result := &ImportResult{
-		TotalSheets:  0,
-		TotalPersons: 0,
-		Details:      []string{},
+// newImportResult 创建空的导入结果
+func newImportResult() *ImportResult {
+	return &ImportResult{
+		TotalSheets:     0,
+		TotalPersons:    0,
+		Details:         []string{},
+		UnmappedHeaders: map[string][]string{},
+	}
+}
+
+// ImportExcelData 导入Excel人员台账
+// 已存在的人员（按身份证号匹配，无身份证号时按楼号+单元+房号+姓名匹配）只更新变化的字段，
+// 不存在的人员新增，重复导入同一文件不会产生重复数据
+// 参数:
+//   - filePath: Excel文件路径
+//   - opts: 导入选项
+//
+// 返回值:
+//   - *ImportResult: 导入结果
+//   - error: 错误信息
+func (s *UpdateExcelDataService) ImportExcelData(filePath string, opts ImportOptions) (*ImportResult, error) {
+	result := newImportResult()
+
+	profile, err := s.GetImportProfile(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets, err := parseWorkbook(filePath, profile, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyImport(sheets, opts, result, nil); err != nil {
+		return result, err
 	}
+	return result, nil
+}
 
-	processings := []string{"101楼", "103楼", "104楼新版", "105楼副本", "106楼副本", "108楼副本", "109楼-更新中", "110楼", "111楼新",
-		"113楼", "114楼更新", "117楼", "118楼新版", "119楼", "120楼新版", "121楼新版", "122楼新版"}
+// parsedSheet 解析后的工作表
+type parsedSheet struct {
+	Name string
+	Data *sheetData
+	Err  error
+}
 
+// parseWorkbook 按导入方案读取工作簿中需要处理的工作表，处理过程写入 result.Details
+func parseWorkbook(filePath string, profile *ImportProfile, result *ImportResult) ([]parsedSheet, error) {
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
@@ -45,62 +110,221 @@ func (s *UpdateExcelDataService) ImportExcelData(filePath string) (*ImportResult
 
 	// 获取所有工作表
 	sheets := f.GetSheetList()
-	result.Details = append(result.Details, fmt.Sprintf("发现 %d 个工作表", len(sheets)))
+	result.Details = append(result.Details, fmt.Sprintf("发现 %d 个工作表，使用导入方案: %s", len(sheets), profile.Name))
 
+	var parsed []parsedSheet
 	for _, sheet := range sheets {
 		// 判断是否需要处理
-		if !slices.Contains(processings, sheet) {
+		layout, ok := profile.Layout(sheet)
+		if !ok {
 			result.Details = append(result.Details, fmt.Sprintf("跳过工作表: %s (不在处理列表中)", sheet))
 			continue
 		}
 
 		result.Details = append(result.Details, fmt.Sprintf("正在处理工作表: %s", sheet))
-		// 读取人员数据（传入文件对象和工作表名称）
-		persons, err := readPersonData(f, sheet)
+		// 读取人员数据（传入文件对象和工作表布局）
+		data, err := readPersonData(f, layout)
 		if err != nil {
 			result.Details = append(result.Details, fmt.Sprintf("读取人员数据失败 [%s]: %v", sheet, err))
+			parsed = append(parsed, parsedSheet{Name: sheet, Err: err})
 			continue
 		}
+		if len(data.UnmappedHeaders) > 0 {
+			result.UnmappedHeaders[sheet] = data.UnmappedHeaders
+			result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 未识别的表头（已忽略）: %s", sheet, strings.Join(data.UnmappedHeaders, "、")))
+		}
 
-		result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 读取 %d 条人员数据", sheet, len(persons)))
-		result.TotalPersons += len(persons)
+		result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 读取 %d 条人员数据", sheet, len(data.Persons)))
+		result.TotalPersons += len(data.Persons)
 		result.TotalSheets++
+		parsed = append(parsed, parsedSheet{Name: sheet, Data: data})
+	}
+	return parsed, nil
+}
 
-		// 保存人员数据
-		if len(persons) > 0 {
-			if err := s.savePersons(persons); err != nil {
-				result.Details = append(result.Details, fmt.Sprintf("保存人员信息失败 [%s]: %v", sheet, err))
+// applyImport 保存解析后的人员数据，处理过程及统计写入 result
+// 每次导入记录一个导入批次，可按批次回滚；opts.Transactional 为 true 时整个导入在一个事务中执行，
+// 任一工作表失败则全部回滚，否则每个工作表单独一个事务
+// onProgress 不为 nil 时在每个工作表开始和结束时回调
+// 返回值为导入整体失败（未写入任何数据）的原因
+func (s *UpdateExcelDataService) applyImport(sheets []parsedSheet, opts ImportOptions, result *ImportResult, onProgress func(SheetProgress)) error {
+	report := func(progress SheetProgress) {
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	batch := &models.ImportBatch{
+		FileName: opts.FileName,
+		Profile:  opts.Profile,
+		Operator: opts.Operator,
+		Status:   models.ImportBatchRunning,
+	}
+	if batch.Profile == "" {
+		batch.Profile = DefaultImportProfileName
+	}
+	if opts.Transactional {
+		batch.Transactional = 1
+	}
+	if err := s.db.Create(batch).Error; err != nil {
+		result.Details = append(result.Details, fmt.Sprintf("创建导入批次失败: %v", err))
+		return fmt.Errorf("创建导入批次失败: %v", err)
+	}
+	result.BatchID = batch.ID
+
+	// 本次导入匹配到的已有人员ID，以及涉及的楼号
+	matchedIDs := make(map[int64]struct{})
+	importedBuildings := make(map[string]struct{})
+	hasFailure := false
+
+	run := func(db *gorm.DB) error {
+		for _, sheet := range sheets {
+			if sheet.Err != nil {
+				report(SheetProgress{Sheet: sheet.Name, Status: SheetProgressFailed, Message: sheet.Err.Error()})
+				if opts.Transactional {
+					return fmt.Errorf("读取工作表 [%s] 失败: %v", sheet.Name, sheet.Err)
+				}
+				hasFailure = true
+				continue
+			}
+			persons := sheet.Data.Persons
+			if len(persons) == 0 {
+				report(SheetProgress{Sheet: sheet.Name, Status: SheetProgressCompleted})
+				continue
+			}
+			report(SheetProgress{Sheet: sheet.Name, Status: SheetProgressRunning, Persons: len(persons)})
+
+			// 保存人员数据
+			var stat *upsertStat
+			saveSheet := func(tx *gorm.DB) error {
+				var err error
+				stat, err = upsertPersons(tx, s.access, batch.ID, sheet.Name, sheet.Data, matchedIDs)
+				return err
+			}
+			var err error
+			if opts.Transactional {
+				err = saveSheet(db)
 			} else {
-				result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 成功保存 %d 条数据", sheet, len(persons)))
+				err = db.Transaction(saveSheet)
+			}
+			if err != nil {
+				report(SheetProgress{Sheet: sheet.Name, Status: SheetProgressFailed, Persons: len(persons), Message: err.Error()})
+				if opts.Transactional {
+					return fmt.Errorf("保存工作表 [%s] 失败: %v", sheet.Name, err)
+				}
+				hasFailure = true
+				result.Details = append(result.Details, fmt.Sprintf("保存人员信息失败 [%s]: %v", sheet.Name, err))
+				continue
+			}
+			result.Inserted += stat.Inserted
+			result.Updated += stat.Updated
+			result.Unchanged += stat.Unchanged
+			report(SheetProgress{
+				Sheet:     sheet.Name,
+				Status:    SheetProgressCompleted,
+				Persons:   len(persons),
+				Inserted:  stat.Inserted,
+				Updated:   stat.Updated,
+				Unchanged: stat.Unchanged,
+			})
+			for _, person := range persons {
+				// 无权访问的楼栋不导入，也不删除其中的人员
+				if s.access.CanAccessBuilding(person.BuildingNumber) {
+					importedBuildings[person.BuildingNumber] = struct{}{}
+				}
+			}
+			result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 成功保存 %d 条数据（新增 %d，更新 %d，未变化 %d）",
+				sheet.Name, len(persons), stat.Inserted, stat.Updated, stat.Unchanged))
+			if stat.Skipped > 0 {
+				result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 有 %d 行与之前的行为同一人员，未导入", sheet.Name, stat.Skipped))
+			}
+			if stat.Denied > 0 {
+				result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 有 %d 行属于无权访问的楼栋，未导入", sheet.Name, stat.Denied))
+			}
+			if stat.Bicycles > 0 {
+				result.Details = append(result.Details, fmt.Sprintf("工作表 [%s] 登记电动车 %d 辆", sheet.Name, stat.Bicycles))
 			}
 		}
+
+		// 软删除导入楼栋中表格里已不存在的人员（有工作表失败时跳过，避免误删）
+		if opts.RemoveMissing {
+			if hasFailure {
+				result.Details = append(result.Details, "存在处理失败的工作表，跳过删除表中已不存在的人员")
+			} else if len(importedBuildings) > 0 {
+				removed, err := removeMissingPersons(db, batch.ID, importedBuildings, matchedIDs)
+				if err != nil {
+					if opts.Transactional {
+						return fmt.Errorf("删除表中已不存在的人员失败: %v", err)
+					}
+					result.Details = append(result.Details, fmt.Sprintf("删除表中已不存在的人员失败: %v", err))
+				} else {
+					result.Removed = removed
+					result.Details = append(result.Details, fmt.Sprintf("删除表中已不存在的人员 %d 条", removed))
+				}
+			}
+		}
+		return nil
 	}
 
-	result.Details = append(result.Details, fmt.Sprintf("导入完成，共处理 %d 个工作表，%d 条人员数据", result.TotalSheets, result.TotalPersons))
-	return result, nil
+	var err error
+	if opts.Transactional {
+		err = s.db.Transaction(run)
+	} else {
+		err = run(s.db)
+	}
+
+	if err != nil {
+		result.Inserted, result.Updated, result.Unchanged, result.Removed = 0, 0, 0, 0
+		result.Details = append(result.Details, fmt.Sprintf("导入失败，已全部回滚: %v", err))
+		if dbErr := s.db.Model(batch).Update("status", models.ImportBatchFailed).Error; dbErr != nil {
+			return fmt.Errorf("%v（更新导入批次状态失败: %v）", err, dbErr)
+		}
+		return err
+	}
+
+	// 有工作表处理失败时批次记录为部分完成，成功的工作表已保存
+	status := models.ImportBatchCompleted
+	if hasFailure {
+		status = models.ImportBatchPartial
+	}
+	if err := s.db.Model(batch).Updates(map[string]interface{}{
+		"status":   status,
+		"inserted": result.Inserted,
+		"updated":  result.Updated,
+		"removed":  result.Removed,
+	}).Error; err != nil {
+		result.Details = append(result.Details, fmt.Sprintf("更新导入批次状态失败: %v", err))
+		return fmt.Errorf("更新导入批次状态失败: %v", err)
+	}
+	summary := "导入完成"
+	if hasFailure {
+		summary = "导入部分完成，存在处理失败的工作表"
+	}
+	result.Details = append(result.Details, fmt.Sprintf("%s（批次 %d），共处理 %d 个工作表，%d 条人员数据（新增 %d，更新 %d，未变化 %d，删除 %d）",
+		summary, batch.ID, result.TotalSheets, result.TotalPersons, result.Inserted, result.Updated, result.Unchanged, result.Removed))
+	return nil
 }
 
-// getBuildingNumberFromMergedCells 专门处理B列的合并单元格
-func getBuildingNumberFromMergedCells(f *excelize.File, sheet string, rows [][]string) (map[int]string, error) {
+// getBuildingNumberFromMergedCells 专门处理地址列的合并单元格
+func getBuildingNumberFromMergedCells(f *excelize.File, sheet string, column string) (map[int]string, error) {
 	// 获取所有合并单元格
 	mergeCells, err := f.GetMergeCells(sheet)
 	if err != nil {
 		return nil, err
 	}
 
-	// 只处理B列（第二列）的合并单元格
+	// 只处理地址列的合并单元格
 	buildingMap := make(map[int]string)
 
 	for _, mc := range mergeCells {
 		startCell, endCell := mc.GetStartAxis(), mc.GetEndAxis()
 		value := mc.GetCellValue()
 
-		// 检查是否是B列的合并单元格
+		// 检查是否是地址列的合并单元格
 		startCol, startRow, _ := excelize.SplitCellName(startCell)
 		endCol, endRow, _ := excelize.SplitCellName(endCell)
 
-		// 只处理B列（列名是"B"）
-		if startCol == "B" && endCol == "B" {
+		if startCol == column && endCol == column {
 			// 将这个合并范围内的所有行都映射到同一个楼号值
 			for row := startRow; row <= endRow; row++ {
 				buildingMap[row] = value
@@ -111,14 +335,259 @@ func getBuildingNumberFromMergedCells(f *excelize.File, sheet string, rows [][]s
 	return buildingMap, nil
 }
 
+// parseYesNo 解析是否类单元格：是=1，否=2，其他=0（未知）
+func parseYesNo(value string) int {
+	switch value {
+	case "是":
+		return 1
+	case "否":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// birthDateLayouts 导入时可识别的出生日期格式
+var birthDateLayouts = []string{"2006-01-02", "2006/1/2", "2006.1.2", "20060102", "2006年1月2日", "2006-1-2"}
+
+// parseBirthDate 解析出生日期单元格
+func parseBirthDate(value string) (time.Time, bool) {
+	for _, layout := range birthDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// setPersonField 按字段名将单元格的值写入人员信息
+func setPersonField(person *models.Person, field, value string) {
+	value = strings.TrimSpace(value)
+	switch field {
+	case "name":
+		person.Name = value
+	case "id_card":
+		person.IDCard = value
+	case "age":
+		if num, err := strconv.Atoi(value); err == nil {
+			person.Age = num
+		}
+	case "birth_date":
+		if birthDate, ok := parseBirthDate(value); ok {
+			person.BirthDate = &birthDate
+		}
+	case "gender":
+		if value == "男" {
+			person.Gender = 1
+		} else if value == "女" {
+			person.Gender = 2
+		}
+	//is_permanent tinyint COMMENT '是否常住：0未知，1是，2否',
+	case "is_permanent":
+		person.IsPermanent = parseYesNo(value)
+	case "housing_situation":
+		person.HousingSituation = value
+	case "property_nature":
+		person.PropertyNature = value
+	//registered_residence_type tinyint COMMENT '户籍情况:0.未知1.东湖户籍2.北京市其他户籍3.外地户籍',
+	case "registered_residence_type":
+		if num, err := strconv.Atoi(value); err == nil {
+			person.RegisteredResidenceType = num
+		}
+	case "registered_residence":
+		person.RegisteredResidence = value
+	case "telephone":
+		person.Telephone = value
+	case "first_contact":
+		person.FirstContact = value
+	case "elder_relationship":
+		person.ElderRelationship = value
+	case "elder_contact_phone":
+		person.ElderContactPhone = value
+	case "special_situation":
+		person.SpecialSituation = value
+	case "disability_level":
+		person.DisabilityLevel = value
+	case "is_low_income":
+		person.IsLowIncome = parseYesNo(value)
+	case "is_low_income2":
+		person.IsLowIncome2 = parseYesNo(value)
+	case "is_destitute":
+		person.IsDestitute = parseYesNo(value)
+	case "is_family_planning_special":
+		person.IsFamilyPlanningSpecial = parseYesNo(value)
+	case "disability_category":
+		person.DisabilityCategory = value
+	case "is_living_alone":
+		person.IsLivingAlone = parseYesNo(value)
+	case "is_empty_nest":
+		person.IsEmptyNest = parseYesNo(value)
+	case "is_orphaned":
+		person.IsOrphaned = parseYesNo(value)
+	case "other_situation":
+		person.OtherSituation = value
+	case "is_needs_focus":
+		person.IsNeedsFocus = parseYesNo(value)
+	case "is_in_group":
+		person.IsInGroup = parseYesNo(value)
+	case "is_private_message":
+		person.IsPrivateMessage = parseYesNo(value)
+	case "has_pet":
+		person.HasPet = parseYesNo(value)
+	case "last_contact_time":
+		person.LastContactTime = value
+	case "other_info":
+		person.OtherInfo = value
+	//is_cp tinyint COMMENT '是否党员：0否，1是',
+	case "is_cp":
+		if value == "是" {
+			person.IsCp = 1
+		}
+	case "cp_joining_day":
+		if day, ok := parseBirthDate(value); ok {
+			joiningDay := day.Format("2006-01-02")
+			person.CpJoiningDay = &joiningDay
+		}
+	case "cp_remark":
+		person.CpRemark = value
+	case "nationality":
+		person.Nationality = value
+	case "education":
+		person.Education = value
+	case "license_plate":
+		// 车牌号、品牌型号登记到电动车表，是否有电动车按电动车表同步
+		person.LicensePlate = normalizePlateNumber(value)
+	case "brand_model":
+		person.BrandModel = value
+	}
+}
+
+// parseAddress 按地址格式解析楼号、单元、房号
+func parseAddress(person *models.Person, address string, layout SheetLayout) {
+	switch layout.AddressFormat {
+	case AddressFormatRoom: //单独的房号
+		bNum := layout.BuildingNumber
+		if bNum == "" {
+			bNum = extractNumbers(layout.Name)
+		}
+		person.BuildingNumber = bNum
+		person.UnitNumber = 1
+		person.RoomNumber = strings.TrimSpace(address)
+	case AddressFormatChinese: //楼、单元、房号
+		building, unit, room := parseBuildingAddress(address)
+		person.BuildingNumber = strings.TrimSpace(building)
+		person.UnitNumber, _ = strconv.Atoi(unit)
+		person.RoomNumber = strings.TrimSpace(room)
+	case AddressFormatBuildingUnitRoom: //楼号-单元-房号
+		splitAddress := strings.Split(address, "-")
+		if len(splitAddress) == 3 {
+			person.BuildingNumber = strings.TrimSpace(splitAddress[0])
+			person.UnitNumber, _ = strconv.Atoi(strings.TrimSpace(splitAddress[1]))
+			person.RoomNumber = strings.TrimSpace(splitAddress[2])
+		}
+	default: //楼号-房号
+		parts := strings.SplitN(address, "-", 2)
+		if len(parts) == 2 {
+			person.BuildingNumber = strings.TrimSpace(parts[0])
+			person.UnitNumber = 1
+			person.RoomNumber = strings.TrimSpace(parts[1])
+		}
+	}
+}
+
+// sheetData 从工作表读取的人员数据
+type sheetData struct {
+	Persons         []models.Person // 人员数据
+	Rows            []int           // 人员数据对应的Excel行号
+	Fields          []string        // 工作表中识别到的字段（缺失的字段导入时不覆盖已有数据）
+	UnmappedHeaders []string        // 未识别的表头
+	Issues          []ImportIssue   // 读取时发现的问题（如地址无法解析，该行不导入）
+}
+
+// matchHeader 先按方案中配置的别名匹配表头，再按系统内置的表头别名匹配
+func matchHeader(header string, layout SheetLayout) (string, bool) {
+	normalized := models.NormalizeHeader(header)
+	for field, aliases := range layout.HeaderAliases {
+		for _, alias := range aliases {
+			if models.NormalizeHeader(alias) == normalized {
+				return field, true
+			}
+		}
+	}
+	return models.MatchImportFieldHeader(header)
+}
+
+// ignoredHeaders 不对应任何字段、也不需要提示的表头
+var ignoredHeaders = map[string]bool{"序号": true, "编号": true}
+
+// matchHeaderRow 匹配一行表头，返回 字段 -> 列下标 及未识别的表头
+func matchHeaderRow(row []string, layout SheetLayout, addressIdx int) (map[string]int, []string) {
+	columnIdx := make(map[string]int)
+	var unmapped []string
+	for idx, header := range row {
+		if idx == addressIdx || strings.TrimSpace(header) == "" || ignoredHeaders[models.NormalizeHeader(header)] {
+			continue
+		}
+		field, ok := matchHeader(header, layout)
+		if !ok {
+			unmapped = append(unmapped, strings.TrimSpace(header))
+			continue
+		}
+		if _, exists := columnIdx[field]; exists {
+			// 同一字段出现多列时以第一列为准
+			unmapped = append(unmapped, strings.TrimSpace(header)+"（重复）")
+			continue
+		}
+		columnIdx[field] = idx
+	}
+	return columnIdx, unmapped
+}
+
+// resolveColumns 根据表头确定各字段所在列
+// 优先使用配置的表头行；该行未识别到任何字段时，在数据起始行之前查找识别字段最多的一行
+// 方案中通过 Columns 指定的字段以配置为准
+func resolveColumns(rows [][]string, layout SheetLayout, addressIdx int) (map[string]int, []string, error) {
+	var columnIdx map[string]int
+	var unmapped []string
+
+	headerIdx := layout.HeaderRow - 1
+	if headerIdx >= 0 && headerIdx < len(rows) {
+		columnIdx, unmapped = matchHeaderRow(rows[headerIdx], layout, addressIdx)
+	}
+	if len(columnIdx) == 0 {
+		for i := 0; i < layout.DataStartRow-1 && i < len(rows); i++ {
+			idx, um := matchHeaderRow(rows[i], layout, addressIdx)
+			if len(idx) > len(columnIdx) {
+				columnIdx, unmapped = idx, um
+			}
+		}
+	}
+	if columnIdx == nil {
+		columnIdx = make(map[string]int)
+	}
+
+	for field, col := range layout.Columns {
+		num, err := excelize.ColumnNameToNumber(col)
+		if err != nil {
+			return nil, nil, fmt.Errorf("字段 [%s] 列配置错误: %v", field, err)
+		}
+		columnIdx[field] = num - 1
+	}
+	if len(columnIdx) == 0 {
+		return nil, nil, errors.New("未识别到表头，请检查表头行或导入方案配置")
+	}
+	return columnIdx, unmapped, nil
+}
+
 /*
  * readPersonData 读取excel中人员信息
  * @param f: excelize.File类型的Excel文件对象
- * @param sheet: 要读取的工作表名称
- * @return: 返回Person结构体切片和可能的错误
+ * @param layout: 工作表布局（工作表名称、表头行、地址格式等）
+ * @return: 返回读取到的人员数据、识别到的字段、未识别的表头，以及可能的错误
  */
-func readPersonData(f *excelize.File, sheet string) ([]models.Person, error) {
-	var persons []models.Person
+func readPersonData(f *excelize.File, layout SheetLayout) (*sheetData, error) {
+	data := &sheetData{}
+	sheet := layout.Name
 
 	// 获取所有行
 	rows, err := f.GetRows(sheet)
@@ -126,18 +595,36 @@ func readPersonData(f *excelize.File, sheet string) ([]models.Person, error) {
 		return nil, err
 	}
 
-	// 获取B列合并单元格的映射
-	buildingMap, err := getBuildingNumberFromMergedCells(f, sheet, rows)
+	addressCol, err := excelize.ColumnNameToNumber(layout.AddressColumn)
+	if err != nil {
+		return nil, fmt.Errorf("地址列配置错误: %v", err)
+	}
+	addressIdx := addressCol - 1
+
+	// 按表头确定 字段 -> 列下标
+	columnIdx, unmapped, err := resolveColumns(rows, layout, addressIdx)
+	if err != nil {
+		return nil, err
+	}
+	data.UnmappedHeaders = unmapped
+	for field := range columnIdx {
+		data.Fields = append(data.Fields, field)
+	}
+	sort.Strings(data.Fields)
+
+	// 获取地址列合并单元格的映射
+	buildingMap, err := getBuildingNumberFromMergedCells(f, sheet, layout.AddressColumn)
 	if err != nil {
 		return nil, err
 	}
 
 	// 跳过表头行，从数据行开始读取
-	startRow := 8
+	now := time.Now()
+	startRow := layout.DataStartRow - 1
 	for i := startRow; i < len(rows); i++ {
 		var person models.Person
 
-		// 解析楼号、单元、户号（处理B列的合并单元格）
+		// 解析楼号、单元、户号（处理地址列的合并单元格）
 		excelRowNum := i + 1 // Excel行号是从1开始的
 
 		var buildingNumber string
@@ -146,247 +633,498 @@ func readPersonData(f *excelize.File, sheet string) ([]models.Person, error) {
 		if mergedValue, exists := buildingMap[excelRowNum]; exists {
 			// 如果是合并单元格，使用合并的值
 			buildingNumber = strings.TrimSpace(mergedValue)
-		} else if len(rows[i]) > 1 && rows[i][1] != "" {
-			// 如果不是合并单元格，直接读取B列的值
-			buildingNumber = strings.TrimSpace(rows[i][1])
+		} else if len(rows[i]) > addressIdx && rows[i][addressIdx] != "" {
+			// 如果不是合并单元格，直接读取地址列的值
+			buildingNumber = strings.TrimSpace(rows[i][addressIdx])
 		}
 		// 处理地址分割
 		if buildingNumber != "" {
-			//单独的房号
-			if sheet == "108楼副本" || sheet == "110楼" || sheet == "111楼新" || sheet == "113楼" {
-				bNum := extractNumbers(sheet)
-				person.BuildingNumber = bNum
-				person.UnitNumber = 1
-				person.RoomNumber = strings.TrimSpace(buildingNumber)
-			} else if sheet == "117楼" { //117楼 楼、单元、房号
-				building, unit, room := parseBuildingAddress(buildingNumber)
-				person.BuildingNumber = strings.TrimSpace(building)
-				person.UnitNumber, _ = strconv.Atoi(unit)
-				person.RoomNumber = strings.TrimSpace(room)
-			} else if sheet == "118楼新版" || sheet == "119楼" || sheet == "120楼新版" || sheet == "121楼新版" || sheet == "122楼新版" { //楼号-单元-房号
-				splitAddress := strings.Split(buildingNumber, "-")
-				person.BuildingNumber = strings.TrimSpace(splitAddress[0])
-				person.UnitNumber, _ = strconv.Atoi(strings.TrimSpace(splitAddress[1]))
-				person.RoomNumber = strings.TrimSpace(splitAddress[2])
-			} else { //楼号-房号
-				parts := strings.SplitN(buildingNumber, "-", 2)
-				if len(parts) == 2 {
-					person.BuildingNumber = strings.TrimSpace(parts[0])
-					person.UnitNumber = 1
-					person.RoomNumber = strings.TrimSpace(parts[1])
-				}
+			parseAddress(&person, buildingNumber, layout)
+		}
+
+		// 按列映射解析其他字段
+		for field, idx := range columnIdx {
+			if len(rows[i]) > idx {
+				setPersonField(&person, field, rows[i][idx])
 			}
 		}
-		// 解析姓名
-		if len(rows[i]) > 2 {
-			person.Name = sql.NullString{String: strings.TrimSpace(rows[i][2]), Valid: rows[i][2] != ""}.String
+
+		if person.BuildingNumber == "" || person.RoomNumber == "" {
+			if buildingNumber != "" {
+				data.Issues = append(data.Issues, newImportIssue(sheet, excelRowNum, "address", buildingNumber,
+					ImportIssueError, "地址无法解析，该行未导入"))
+			} else if person.Name != "" {
+				data.Issues = append(data.Issues, newImportIssue(sheet, excelRowNum, "address", "",
+					ImportIssueError, "缺少地址，该行未导入"))
+			}
+			continue
+		}
+		// 身份证号有效时按身份证号计算性别、年龄（15位号码转换为18位）
+		data.Issues = append(data.Issues, applyImportIDCard(sheet, excelRowNum, &person, now)...)
+		data.Persons = append(data.Persons, person)
+		data.Rows = append(data.Rows, excelRowNum)
+	}
+	return data, nil
+}
+
+// upsertStat 单个工作表的保存统计
+type upsertStat struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Skipped   int // 与同一工作表中之前的行为同一人员而跳过的行数
+	Denied    int // 属于无权访问的楼栋而跳过的行数
+	Bicycles  int // 新登记的电动车数
+}
+
+// personMatchKey 无身份证号时用于匹配人员的键：楼号+单元+房号+姓名
+func personMatchKey(p *models.Person) string {
+	return fmt.Sprintf("%s|%d|%s|%s", p.BuildingNumber, p.UnitNumber, p.RoomNumber, p.Name)
+}
+
+// personImportValues 返回导入时维护的字段（列名 -> 值）
+func personImportValues(p *models.Person) map[string]interface{} {
+	return map[string]interface{}{
+		"building_number":            p.BuildingNumber,
+		"unit_number":                p.UnitNumber,
+		"room_number":                p.RoomNumber,
+		"name":                       p.Name,
+		"id_card":                    p.IDCard,
+		"age":                        p.Age,
+		"birth_date":                 p.BirthDateValue(),
+		"gender":                     p.Gender,
+		"is_permanent":               p.IsPermanent,
+		"housing_situation":          p.HousingSituation,
+		"property_nature":            p.PropertyNature,
+		"registered_residence_type":  p.RegisteredResidenceType,
+		"registered_residence":       p.RegisteredResidence,
+		"telephone":                  p.Telephone,
+		"first_contact":              p.FirstContact,
+		"elder_relationship":         p.ElderRelationship,
+		"elder_contact_phone":        p.ElderContactPhone,
+		"disability_level":           p.DisabilityLevel,
+		"is_low_income":              p.IsLowIncome,
+		"is_low_income2":             p.IsLowIncome2,
+		"is_destitute":               p.IsDestitute,
+		"is_family_planning_special": p.IsFamilyPlanningSpecial,
+		"disability_category":        p.DisabilityCategory,
+		"is_living_alone":            p.IsLivingAlone,
+		"is_empty_nest":              p.IsEmptyNest,
+		"is_orphaned":                p.IsOrphaned,
+		"other_situation":            p.OtherSituation,
+		"is_needs_focus":             p.IsNeedsFocus,
+		"is_in_group":                p.IsInGroup,
+		"is_private_message":         p.IsPrivateMessage,
+		"has_pet":                    p.HasPet,
+		"last_contact_time":          p.LastContactTime,
+		"other_info":                 p.OtherInfo,
+		"is_cp":                      p.IsCp,
+		"cp_joining_day":             p.CpJoiningDayValue(),
+		"cp_remark":                  p.CpRemark,
+		"nationality":                p.Nationality,
+		"education":                  p.Education,
+	}
+}
+
+// diffPersonValues 比较导入数据与已有数据，返回发生变化的字段
+// 只比较地址字段和 fields 中的字段，工作表中缺失的列不会覆盖已有数据
+func diffPersonValues(existing, incoming *models.Person, fields []string) map[string]interface{} {
+	oldValues := personImportValues(existing)
+	newValues := personImportValues(incoming)
+	columns := append([]string{"building_number", "unit_number", "room_number"}, fields...)
+	// 出生日期、性别、年龄按有效身份证号计算，表中没有对应列时同样更新
+	if idcard.Valid(incoming.IDCard) {
+		columns = append(columns, "birth_date", "gender", "age")
+	}
+	changes := make(map[string]interface{})
+	for _, column := range columns {
+		value, ok := newValues[column]
+		if !ok {
+			continue
 		}
-		// 解析身份证
-		if len(rows[i]) > 3 {
-			person.IDCard = sql.NullString{String: strings.TrimSpace(rows[i][3]), Valid: rows[i][3] != ""}.String
+		if oldValues[column] != value {
+			changes[column] = value
 		}
+	}
+	return changes
+}
+
+// personMatcher 在已有人员中查找与导入数据匹配的记录
+type personMatcher struct {
+	byIDCard map[string]*models.Person
+	byKey    map[string]*models.Person
+}
 
-		// 解析年龄
-		if len(rows[i]) > 4 && rows[i][4] != "" {
-			num, err := strconv.Atoi(rows[i][4])
-			if err == nil {
-				person.Age = num
+func newPersonMatcher(existing []models.Person) *personMatcher {
+	m := &personMatcher{
+		byIDCard: make(map[string]*models.Person),
+		byKey:    make(map[string]*models.Person),
+	}
+	for i := range existing {
+		person := &existing[i]
+		if person.IDCard != "" {
+			// 已有数据中可能是15位或小写校验码的号码，统一格式后匹配
+			idCard := normalizeIDCard(person.IDCard)
+			if _, ok := m.byIDCard[idCard]; !ok {
+				m.byIDCard[idCard] = person
 			}
 		}
+		key := personMatchKey(person)
+		if _, ok := m.byKey[key]; !ok {
+			m.byKey[key] = person
+		}
+	}
+	return m
+}
 
-		// 解析性别
-		if len(rows[i]) > 5 {
-			gender := strings.TrimSpace(rows[i][5])
-			if gender == "男" {
-				person.Gender = 1
-			} else if gender == "女" {
-				person.Gender = 2
-			}
+// add 登记待新增的人员，使同一工作表中后续相同的人员能匹配到
+func (m *personMatcher) add(p *models.Person) {
+	if p.IDCard != "" {
+		if _, ok := m.byIDCard[p.IDCard]; !ok {
+			m.byIDCard[p.IDCard] = p
 		}
+	}
+	key := personMatchKey(p)
+	if _, ok := m.byKey[key]; !ok {
+		m.byKey[key] = p
+	}
+}
 
-		//是否常驻
-		//is_permanent tinyint COMMENT '是否常住：0未知，1是，2否',
-		if len(rows[i]) > 6 {
-			isPermanent := strings.TrimSpace(rows[i][6])
-			if isPermanent == "是" {
-				person.IsPermanent = 1 // 注意：这里原来是 person.Gender = 1，应该是笔误
-			} else if isPermanent == "否" {
-				person.IsPermanent = 2 // 注意：这里原来是 person.Gender = 2，应该是笔误
-			}
+// match 先按身份证号匹配，再按楼号+单元+房号+姓名匹配
+func (m *personMatcher) match(p *models.Person) *models.Person {
+	if p.IDCard != "" {
+		if existing, ok := m.byIDCard[p.IDCard]; ok {
+			return existing
 		}
+	}
+	return m.byKey[personMatchKey(p)]
+}
 
-		// housing_situation VARCHAR(100) COMMENT '住房情况',
-		if len(rows[i]) > 7 {
-			person.HousingSituation = sql.NullString{String: strings.TrimSpace(rows[i][7]), Valid: rows[i][7] != ""}.String
+// loadMatchCandidates 查询可能与导入数据匹配的已有人员（同楼号或同身份证号）
+func loadMatchCandidates(db *gorm.DB, persons []models.Person) ([]models.Person, error) {
+	buildingSet := make(map[string]struct{})
+	var buildings, idCards, idCardIndexes []string
+	for _, person := range persons {
+		if _, ok := buildingSet[person.BuildingNumber]; !ok {
+			buildingSet[person.BuildingNumber] = struct{}{}
+			buildings = append(buildings, person.BuildingNumber)
+		}
+		if person.IDCard != "" {
+			idCards = append(idCards, person.IDCard)
+			idCardIndexes = append(idCardIndexes, fieldcrypt.BlindIndex(person.IDCard))
 		}
+	}
+
+	var existing []models.Person
+	query := db.Where("is_del = 0")
+	if len(idCards) > 0 {
+		// 身份证号加密存储，按盲索引匹配（同时匹配尚未迁移的明文数据）
+		query = query.Where(db.Where("building_number IN ?", buildings).
+			Or("id_card_bidx IN ?", idCardIndexes).Or("id_card IN ?", idCards))
+	} else {
+		query = query.Where("building_number IN ?", buildings)
+	}
+	err := query.Order("id").Find(&existing).Error
+	return existing, err
+}
 
-		//property_nature VARCHAR(200) COMMENT '房屋性质',
-		if len(rows[i]) > 8 {
-			person.PropertyNature = sql.NullString{String: strings.TrimSpace(rows[i][8]), Valid: rows[i][8] != ""}.String
+// personUpdate 需要更新的已有人员
+type personUpdate struct {
+	Row     int                    // Excel行号
+	Old     *models.Person         // 已有数据
+	Changes map[string]interface{} // 变化的字段
+}
+
+// upsertPlan 单个工作表的保存计划
+type upsertPlan struct {
+	Inserts    []models.Person // 需要新增的人员
+	InsertRows []int           // 新增人员对应的Excel行号
+	Updates    []personUpdate  // 需要更新的人员
+	Unchanged  int             // 未变化人数
+	Skipped    int             // 与之前的行为同一人员而跳过的行数
+	Denied     int             // 属于无权访问的楼栋而跳过的行数
+	Bicycles   []bicycleImport // 需要登记的电动车
+	Issues     []ImportIssue   // 同一工作表中重复人员、无权访问楼栋、电动车无法登记的提示
+}
+
+// planUpsert 将工作表数据与已有人员比对，生成保存计划（不写入数据库）
+// 同一工作表中多行为同一人员时只保留第一行，其余行跳过并提示；
+// 行中的楼栋或匹配到的已有人员所在楼栋无权访问时，该行跳过并提示
+// 参数:
+//   - db: 数据库连接
+//   - access: 当前用户权限范围，为 nil 时不限制
+//   - sheet: 工作表名称
+//   - data: 从工作表读取的人员数据
+//   - matchedIDs: 本次导入已匹配的人员ID（会被写入）
+//
+// 返回值:
+//   - *upsertPlan: 保存计划
+//   - error: 错误信息
+func planUpsert(db *gorm.DB, access *UserAccess, sheet string, data *sheetData, matchedIDs map[int64]struct{}) (*upsertPlan, error) {
+	plan := &upsertPlan{}
+	if len(data.Persons) == 0 {
+		return plan, nil
+	}
+
+	existing, err := loadMatchCandidates(db, data.Persons)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newPersonMatcher(existing)
+
+	// 本工作表中已出现的人员（已有人员或待新增的行）及其所在行号
+	firstRows := make(map[*models.Person]int)
+	var bicycles []bicycleImport
+	for i := range data.Persons {
+		person := &data.Persons[i]
+		old := matcher.match(person)
+		if denied := deniedBuilding(access, person, old); denied != "" {
+			plan.Denied++
+			plan.Issues = append(plan.Issues, newImportIssue(sheet, data.Rows[i], "building_number", denied, ImportIssueError,
+				fmt.Sprintf("无权访问楼栋 %s，该行不导入", denied)))
+			continue
 		}
-		//registered_residence_type tinyint COMMENT '户籍情况:0.未知1.东湖户籍2.北京市其他户籍3.外地户籍',
-		if len(rows[i]) > 9 && rows[i][9] != "" {
-			num, err := strconv.Atoi(rows[i][9])
-			if err == nil {
-				person.RegisteredResidenceType = num
-			}
+		if row, ok := firstRows[old]; old != nil && ok {
+			plan.Skipped++
+			plan.Issues = append(plan.Issues, newImportIssue(sheet, data.Rows[i], "name", person.Name, ImportIssueWarning,
+				fmt.Sprintf("与第 %d 行为同一人员，该行不导入", row)))
+			continue
 		}
-		//registered_residence VARCHAR(200) COMMENT '户籍地',
-		if len(rows[i]) > 10 {
-			person.RegisteredResidence = sql.NullString{String: strings.TrimSpace(rows[i][10]), Valid: rows[i][10] != ""}.String
-		}
-		//telephone VARCHAR(50) COMMENT '联系方式',
-		if len(rows[i]) > 11 {
-			person.Telephone = sql.NullString{String: strings.TrimSpace(rows[i][11]), Valid: rows[i][11] != ""}.String
-		}
-		//first_contact VARCHAR(50) COMMENT '第一联系人',
-		if len(rows[i]) > 12 {
-			person.FirstContact = sql.NullString{String: strings.TrimSpace(rows[i][12]), Valid: rows[i][12] != ""}.String
-		}
-		//elder_relationship VARCHAR(50) COMMENT '关系(老人之)',
-		if len(rows[i]) > 13 {
-			person.ElderRelationship = sql.NullString{String: strings.TrimSpace(rows[i][13]), Valid: rows[i][13] != ""}.String
-		}
-		//elder_contact_phone VARCHAR(20) COMMENT '联系电话',
-		if len(rows[i]) > 14 {
-			person.ElderContactPhone = sql.NullString{String: strings.TrimSpace(rows[i][14]), Valid: rows[i][14] != ""}.String
-		}
-		//disability_level VARCHAR(100) COMMENT '失能等级',
-		if len(rows[i]) > 15 {
-			person.DisabilityLevel = sql.NullString{String: strings.TrimSpace(rows[i][15]), Valid: rows[i][15] != ""}.String
-		}
-		//is_low_income tinyint COMMENT '是否低保2否，1是',
-		if len(rows[i]) > 16 {
-			num := strings.TrimSpace(rows[i][16])
-			if num == "是" {
-				person.IsLowIncome = 1
-			} else if num == "否" {
-				person.IsLowIncome = 2
+		if old == nil {
+			if person.LicensePlate != "" {
+				bicycles = append(bicycles, bicycleImport{Row: data.Rows[i], Insert: len(plan.Inserts),
+					PlateNumber: person.LicensePlate, BrandModel: person.BrandModel})
 			}
+			plan.Inserts = append(plan.Inserts, *person)
+			plan.InsertRows = append(plan.InsertRows, data.Rows[i])
+			matcher.add(person)
+			firstRows[person] = data.Rows[i]
+			continue
 		}
-		//is_low_income2 tinyint COMMENT '是否低收入2否，1是',
-		if len(rows[i]) > 17 {
-			num := strings.TrimSpace(rows[i][17])
-			if num == "是" {
-				person.IsLowIncome2 = 1
-			} else if num == "否" {
-				person.IsLowIncome2 = 2
-			}
+		firstRows[old] = data.Rows[i]
+		matchedIDs[old.ID] = struct{}{}
+		if person.LicensePlate != "" {
+			bicycles = append(bicycles, bicycleImport{Row: data.Rows[i], PersonID: old.ID,
+				PlateNumber: person.LicensePlate, BrandModel: person.BrandModel})
 		}
-		//is_destitute tinyint COMMENT '是否特困供养2否，1是',
-		if len(rows[i]) > 18 {
-			num := strings.TrimSpace(rows[i][18])
-			if num == "是" {
-				person.IsDestitute = 1
-			} else if num == "否" {
-				person.IsDestitute = 2
-			}
+		changes := diffPersonValues(old, person, data.Fields)
+		if len(changes) == 0 {
+			plan.Unchanged++
+			continue
 		}
-		//is_family_planning_special tinyint COMMENT '是否计划生育特殊家庭2否，1是',
-		if len(rows[i]) > 19 {
-			num := strings.TrimSpace(rows[i][19])
-			if num == "是" {
-				person.IsFamilyPlanningSpecial = 1
-			} else if num == "否" {
-				person.IsFamilyPlanningSpecial = 2
-			}
+		plan.Updates = append(plan.Updates, personUpdate{Row: data.Rows[i], Old: old, Changes: changes})
+	}
+	if err := planBicycles(db, sheet, plan, bicycles); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// deniedBuilding 返回导入行涉及的无权访问的楼号（行中的楼号或匹配到的已有人员所在楼号），都可以访问时返回空
+func deniedBuilding(access *UserAccess, person, old *models.Person) string {
+	if !access.CanAccessBuilding(person.BuildingNumber) {
+		return person.BuildingNumber
+	}
+	if old != nil && !access.CanAccessBuilding(old.BuildingNumber) {
+		return old.BuildingNumber
+	}
+	return ""
+}
+
+// upsertPersons 保存人员数据：匹配到的已有人员更新变化的字段，未匹配到的新增，变更记录到导入批次
+// 参数:
+//   - db: 数据库连接（事务）
+//   - access: 当前用户权限范围，为 nil 时不限制
+//   - batchID: 导入批次ID
+//   - sheet: 工作表名称
+//   - data: 从工作表读取的人员数据
+//   - matchedIDs: 本次导入已匹配的人员ID（会被写入）
+//
+// 返回值:
+//   - *upsertStat: 新增/更新/未变化统计
+//   - error: 错误信息
+func upsertPersons(db *gorm.DB, access *UserAccess, batchID int64, sheet string, data *sheetData, matchedIDs map[int64]struct{}) (*upsertStat, error) {
+	plan, err := planUpsert(db, access, sheet, data, matchedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 人员按楼号、单元、房号关联房间，房间不存在时创建
+	resolver := newRoomResolver(db)
+	var changes []models.ImportBatchChange
+	for _, update := range plan.Updates {
+		oldValues := personImportValues(update.Old)
+		previous := make(map[string]interface{}, len(update.Changes))
+		for column := range update.Changes {
+			previous[column] = oldValues[column]
 		}
-		//disability_category VARCHAR(100) COMMENT '残疾类别及等级',
-		if len(rows[i]) > 20 {
-			person.DisabilityCategory = sql.NullString{String: strings.TrimSpace(rows[i][20]), Valid: rows[i][20] != ""}.String
-		}
-		//is_living_alone tinyint COMMENT '是否独居2否1是',
-		if len(rows[i]) > 21 {
-			num := strings.TrimSpace(rows[i][21])
-			if num == "是" {
-				person.IsLivingAlone = 1
-			} else if num == "否" {
-				person.IsLivingAlone = 2
-			}
+		// 敏感字段加密后写入，回滚记录中同样只保存密文
+		if err := models.EncryptPersonValues(update.Changes); err != nil {
+			return nil, err
 		}
-		//is_empty_nest tinyint COMMENT '是否空巢2否1是',
-		if len(rows[i]) > 22 {
-			num := strings.TrimSpace(rows[i][22])
-			if num == "是" {
-				person.IsEmptyNest = 1
-			} else if num == "否" {
-				person.IsEmptyNest = 2
-			}
+		if err := models.EncryptPersonValues(previous); err != nil {
+			return nil, err
 		}
-		//is_orphaned tinyint COMMENT '是否孤寡2否1是',
-		if len(rows[i]) > 23 {
-			num := strings.TrimSpace(rows[i][23])
-			if num == "是" {
-				person.IsOrphaned = 1
-			} else if num == "否" {
-				person.IsOrphaned = 2
-			}
+		if err := db.Model(&models.Person{}).Where("id = ?", update.Old.ID).Updates(update.Changes).Error; err != nil {
+			return nil, err
 		}
-		//other_situation TEXT COMMENT '其它情况',
-		if len(rows[i]) > 24 {
-			person.OtherSituation = sql.NullString{String: strings.TrimSpace(rows[i][24]), Valid: rows[i][24] != ""}.String
-		}
-		//is_needs_focus tinyint COMMENT '是否需重点关注2否1是',
-		if len(rows[i]) > 25 {
-			num := strings.TrimSpace(rows[i][25])
-			if num == "是" {
-				person.IsNeedsFocus = 1
-			} else if num == "否" {
-				person.IsNeedsFocus = 2
+		if hasAddressChange(update.Changes) {
+			if err := syncPersonRoom(db, resolver, update.Old.ID); err != nil {
+				return nil, err
 			}
 		}
-		//is_in_group tinyint COMMENT '是否入群2否1是',
-		if len(rows[i]) > 28 {
-			num := strings.TrimSpace(rows[i][28])
-			if num == "是" {
-				person.IsInGroup = 1
-			} else if num == "否" {
-				person.IsInGroup = 2
-			}
+		encoded, err := json.Marshal(previous)
+		if err != nil {
+			return nil, err
 		}
-		//is_private_message tinyint COMMENT '是否私信2否1是',
-		if len(rows[i]) > 29 {
-			num := strings.TrimSpace(rows[i][29])
-			if num == "是" {
-				person.IsPrivateMessage = 1
-			} else if num == "否" {
-				person.IsPrivateMessage = 2
+		written, err := json.Marshal(update.Changes)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.ImportBatchChange{
+			BatchID:   batchID,
+			PersonID:  update.Old.ID,
+			Action:    models.ImportChangeUpdate,
+			OldValues: string(encoded),
+			NewValues: string(written),
+		})
+	}
+
+	if len(plan.Inserts) > 0 {
+		for i := range plan.Inserts {
+			person := &plan.Inserts[i]
+			roomID, err := resolver.resolve(person.BuildingNumber, person.UnitNumber, person.RoomNumber)
+			if err != nil {
+				return nil, err
 			}
+			person.RoomID = roomID
 		}
-		//has_pet tinyint COMMENT '家有宠物2否1是',
-		if len(rows[i]) > 30 {
-			num := strings.TrimSpace(rows[i][30])
-			if num == "是" {
-				person.HasPet = 1
-			} else if num == "否" {
-				person.HasPet = 2
+		// 是否有电动车、车牌号、品牌型号按登记的电动车同步
+		if err := db.Omit("has_electric_car", "license_plate", "brand_model").CreateInBatches(plan.Inserts, 500).Error; err != nil {
+			return nil, err
+		}
+		for i := range plan.Inserts {
+			person := &plan.Inserts[i]
+			matchedIDs[person.ID] = struct{}{}
+			values := personImportValues(person)
+			if err := models.EncryptPersonValues(values); err != nil {
+				return nil, err
+			}
+			written, err := json.Marshal(values)
+			if err != nil {
+				return nil, err
 			}
+			changes = append(changes, models.ImportBatchChange{
+				BatchID:   batchID,
+				PersonID:  person.ID,
+				Action:    models.ImportChangeInsert,
+				NewValues: string(written),
+			})
 		}
-		//last_contact_time VARCHAR(50) COMMENT '末次联系时间',
-		if len(rows[i]) > 31 {
-			lastTime := strings.TrimSpace(rows[i][31])
-			person.LastContactTime = lastTime
+	}
+
+	// 登记表中的电动车，电动车ID记录在变更中以便回滚
+	bicycles, err := createImportBicycles(db, plan)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if ids, ok := bicycles[changes[i].PersonID]; ok {
+			if changes[i].NewValues, err = withBicycleIDs(changes[i].NewValues, ids); err != nil {
+				return nil, err
+			}
+			delete(bicycles, changes[i].PersonID)
 		}
-		//other_info TEXT COMMENT '其他',
-		if len(rows[i]) > 32 {
-			person.OtherInfo = strings.TrimSpace(rows[i][32])
+	}
+	// 人员信息未变化、只登记了电动车的人员
+	personIDs := make([]int64, 0, len(bicycles))
+	for personID := range bicycles {
+		personIDs = append(personIDs, personID)
+	}
+	sort.Slice(personIDs, func(i, j int) bool { return personIDs[i] < personIDs[j] })
+	for _, personID := range personIDs {
+		newValues, err := withBicycleIDs("", bicycles[personID])
+		if err != nil {
+			return nil, err
 		}
-		if person.BuildingNumber == "" {
-			fmt.Println(person)
-			continue
+		changes = append(changes, models.ImportBatchChange{
+			BatchID:   batchID,
+			PersonID:  personID,
+			Action:    models.ImportChangeUpdate,
+			OldValues: "{}",
+			NewValues: newValues,
+		})
+	}
+
+	if len(changes) > 0 {
+		if err := db.CreateInBatches(changes, 500).Error; err != nil {
+			return nil, err
 		}
-		persons = append(persons, person)
 	}
-	return persons, nil
+	return &upsertStat{
+		Inserted:  len(plan.Inserts),
+		Updated:   len(plan.Updates),
+		Unchanged: plan.Unchanged,
+		Skipped:   plan.Skipped,
+		Denied:    plan.Denied,
+		Bicycles:  len(plan.Bicycles),
+	}, nil
 }
 
-// savePersonInfo 将人员信息保存到数据库中
-func (s *UpdateExcelDataService) savePersonInfo(person models.Person) error {
-	return s.db.Create(&person).Error
+// missingPersonsQuery 导入楼栋中本次未匹配到的人员
+func missingPersonsQuery(db *gorm.DB, buildings map[string]struct{}, matchedIDs map[int64]struct{}) *gorm.DB {
+	var buildingList []string
+	for building := range buildings {
+		buildingList = append(buildingList, building)
+	}
+	var ids []int64
+	for id := range matchedIDs {
+		ids = append(ids, id)
+	}
+
+	query := db.Model(&models.Person{}).Where("is_del = 0 AND building_number IN ?", buildingList)
+	if len(ids) > 0 {
+		query = query.Where("id NOT IN ?", ids)
+	}
+	return query
 }
 
-func (s *UpdateExcelDataService) savePersons(persons []models.Person) error {
-	return s.db.CreateInBatches(persons, 500).Error
+// removeMissingPersons 软删除导入楼栋中本次未匹配到的人员及其名下的电动车，变更记录到导入批次
+func removeMissingPersons(db *gorm.DB, batchID int64, buildings map[string]struct{}, matchedIDs map[int64]struct{}) (int, error) {
+	var ids []int64
+	if err := missingPersonsQuery(db, buildings, matchedIDs).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := db.Model(&models.Person{}).Where("id IN ?", ids).Update("is_del", 1).Error; err != nil {
+		return 0, err
+	}
+	// 名下的电动车同时软删除，回滚时按记录的电动车ID恢复
+	bicycles, err := removePersonsElectricBicycles(db, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	changes := make([]models.ImportBatchChange, 0, len(ids))
+	for _, id := range ids {
+		change := models.ImportBatchChange{
+			BatchID:  batchID,
+			PersonID: id,
+			Action:   models.ImportChangeRemove,
+		}
+		if bicycleIDs := bicycles[id]; len(bicycleIDs) > 0 {
+			if change.OldValues, err = withBicycleIDs("", bicycleIDs); err != nil {
+				return 0, err
+			}
+		}
+		changes = append(changes, change)
+	}
+	if err := db.CreateInBatches(changes, 500).Error; err != nil {
+		return 0, err
+	}
+	return len(ids), nil
 }
 
 /**
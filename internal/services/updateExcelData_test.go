@@ -0,0 +1,105 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"PLMS/internal/models"
+)
+
+func TestDiffPersonValues(t *testing.T) {
+	existing := &models.Person{
+		BuildingNumber: "1",
+		UnitNumber:     1,
+		RoomNumber:     "101",
+		Name:           "张三",
+		Telephone:      "13812345678",
+		OtherInfo:      "原备注",
+	}
+	tests := []struct {
+		name     string
+		incoming models.Person
+		fields   []string
+		want     map[string]interface{}
+	}{
+		{
+			name:     "数据未变化",
+			incoming: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "101", Name: "张三", Telephone: "13812345678"},
+			fields:   []string{"name", "telephone"},
+			want:     map[string]interface{}{},
+		},
+		{
+			name:     "电话变化",
+			incoming: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "101", Name: "张三", Telephone: "13987654321"},
+			fields:   []string{"name", "telephone"},
+			want:     map[string]interface{}{"telephone": "13987654321"},
+		},
+		{
+			name:     "工作表中缺失的列不覆盖已有数据",
+			incoming: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "101", Name: "张三"},
+			fields:   []string{"name"},
+			want:     map[string]interface{}{},
+		},
+		{
+			name:     "地址字段总是比较",
+			incoming: models.Person{BuildingNumber: "1", UnitNumber: 2, RoomNumber: "201", Name: "张三"},
+			fields:   []string{"name"},
+			want:     map[string]interface{}{"unit_number": 2, "room_number": "201"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffPersonValues(existing, &tt.incoming, tt.fields)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffPersonValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersonMatcher(t *testing.T) {
+	existing := []models.Person{
+		{ID: 1, BuildingNumber: "1", UnitNumber: 1, RoomNumber: "101", Name: "张三", IDCard: "11010519491231002X"},
+		{ID: 2, BuildingNumber: "1", UnitNumber: 1, RoomNumber: "102", Name: "李四"},
+		// 与 ID 2 重复的记录，匹配时使用第一条
+		{ID: 3, BuildingNumber: "1", UnitNumber: 1, RoomNumber: "102", Name: "李四"},
+	}
+	matcher := newPersonMatcher(existing)
+
+	tests := []struct {
+		name   string
+		person models.Person
+		wantID int64
+	}{
+		{name: "按身份证号匹配（地址已变化）", person: models.Person{BuildingNumber: "2", UnitNumber: 1, RoomNumber: "301", Name: "张三", IDCard: "11010519491231002X"}, wantID: 1},
+		{name: "按楼号单元房号姓名匹配", person: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "101", Name: "张三"}, wantID: 1},
+		{name: "重复记录匹配第一条", person: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "102", Name: "李四"}, wantID: 2},
+		{name: "未匹配", person: models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "103", Name: "王五"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matcher.match(&tt.person)
+			if tt.wantID == 0 {
+				if got != nil {
+					t.Errorf("match() = %d, 应未匹配", got.ID)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.wantID {
+				t.Errorf("match() = %v, want ID %d", got, tt.wantID)
+			}
+		})
+	}
+
+	// 同一工作表中待新增的人员登记后，后续相同的行匹配到同一人员
+	added := models.Person{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "103", Name: "王五", IDCard: "110105194912310037"}
+	matcher.add(&added)
+	for _, person := range []models.Person{
+		{BuildingNumber: "1", UnitNumber: 1, RoomNumber: "103", Name: "王五"},
+		{BuildingNumber: "3", UnitNumber: 1, RoomNumber: "101", Name: "王五", IDCard: "110105194912310037"},
+	} {
+		if got := matcher.match(&person); got != &added {
+			t.Errorf("match(%s %s) 应匹配到同一工作表中待新增的人员", person.RoomNumber, person.IDCard)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+-- 为 person 表添加出生日期字段
+-- 年龄不再单独维护，有出生日期时查询时按出生日期计算；已有数据在服务启动时按身份证号补充出生日期
+
+ALTER TABLE person
+ADD COLUMN birth_date DATE NULL COMMENT '出生日期（有效身份证号时按身份证号计算）'
+AFTER age;
+
+ALTER TABLE person
+ADD COLUMN birth_date_checked TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否已按身份证号补充过出生日期（身份证号无效的不再重复解析）'
+AFTER birth_date;
+
+ALTER TABLE person
+ADD INDEX idx_birth_date (birth_date);
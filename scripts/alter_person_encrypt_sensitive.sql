@@ -0,0 +1,23 @@
+-- 敏感字段加密存储
+-- 身份证号、电话、紧急联系电话加密后长度增加，列宽改为 255；新增盲索引列用于精确查询
+-- 执行后运行 go run ./cmd/encrypt-persons 加密已有数据
+
+ALTER TABLE person
+MODIFY COLUMN id_card VARCHAR(255) NULL COMMENT '身份证号（加密存储）',
+MODIFY COLUMN telephone VARCHAR(255) NULL COMMENT '联系方式（加密存储）',
+MODIFY COLUMN elder_contact_phone VARCHAR(255) NULL COMMENT '联系电话（加密存储）';
+
+-- 身份证号、电话的盲索引（HMAC-SHA256）
+ALTER TABLE person
+ADD COLUMN id_card_bidx CHAR(64) NOT NULL DEFAULT '' COMMENT '身份证号盲索引' AFTER id_card,
+ADD COLUMN telephone_bidx CHAR(64) NOT NULL DEFAULT '' COMMENT '电话盲索引' AFTER telephone;
+
+-- 密文无法按值查询，原身份证号索引改为盲索引
+ALTER TABLE person
+DROP INDEX idx_id_card,
+ADD INDEX idx_id_card_bidx (id_card_bidx),
+ADD INDEX idx_telephone_bidx (telephone_bidx);
+
+-- 审计日志的查询条件及修改前后数据同样加密存储，密文长度增加
+ALTER TABLE audit_log
+MODIFY COLUMN filter MEDIUMTEXT NULL COMMENT '查询条件（查询参数及请求体，加密存储）';
@@ -0,0 +1,11 @@
+-- 为 sys_user 表添加登录失败锁定字段
+
+-- 连续登录失败次数（登录成功或管理员解锁后清零）
+ALTER TABLE sys_user
+ADD COLUMN failed_login_count INT DEFAULT 0 COMMENT '连续登录失败次数'
+AFTER status;
+
+-- 锁定截止时间（为空或早于当前时间表示未锁定）
+ALTER TABLE sys_user
+ADD COLUMN locked_until DATETIME NULL COMMENT '锁定截止时间'
+AFTER failed_login_count;
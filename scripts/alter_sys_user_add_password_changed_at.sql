@@ -0,0 +1,8 @@
+-- 为 sys_user 表添加密码最后修改时间（密码过期策略）
+
+ALTER TABLE sys_user
+ADD COLUMN password_changed_at DATETIME NULL COMMENT '密码最后修改时间'
+AFTER is_default_password;
+
+-- 已有用户从执行脚本时开始计算有效期
+UPDATE sys_user SET password_changed_at = NOW() WHERE password_changed_at IS NULL;
@@ -0,0 +1,6 @@
+-- TOTP 密钥加密存储
+-- 加密后长度增加，列宽改为 255
+-- 执行后运行 go run ./cmd/encrypt-persons 加密已有的密钥
+
+ALTER TABLE sys_user
+MODIFY COLUMN totp_secret VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'TOTP密钥（Base32，加密存储）';
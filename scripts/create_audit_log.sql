@@ -0,0 +1,30 @@
+-- 审计日志（住户数据的查看、导出、修改记录）
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NULL COMMENT '操作用户ID',
+    username VARCHAR(50) NULL COMMENT '操作用户名',
+    role VARCHAR(50) NULL COMMENT '操作用户角色',
+    action VARCHAR(20) NOT NULL COMMENT '操作类型：list/view/export/create/update/delete/import',
+    resource VARCHAR(20) NOT NULL COMMENT '操作对象：person/bicycle/import',
+    method VARCHAR(10) NULL COMMENT '请求方法',
+    endpoint VARCHAR(255) NULL COMMENT '请求路径',
+    client_ip VARCHAR(64) NULL COMMENT '客户端IP',
+    filter TEXT NULL COMMENT '查询条件（查询参数及请求体）',
+    before_data MEDIUMTEXT NULL COMMENT '修改前数据（JSON）',
+    after_data MEDIUMTEXT NULL COMMENT '修改后数据（JSON）',
+    status_code INT NULL COMMENT '响应状态码',
+    person_num INT DEFAULT 0 COMMENT '涉及人员数',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_id (user_id),
+    INDEX idx_action (action),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='审计日志';
+
+CREATE TABLE IF NOT EXISTS audit_log_person (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    audit_id BIGINT NOT NULL COMMENT '审计日志ID',
+    person_id BIGINT NOT NULL COMMENT '人员ID',
+    INDEX idx_audit_id (audit_id),
+    INDEX idx_person_id (person_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='审计日志涉及的人员';
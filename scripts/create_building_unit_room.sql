@@ -0,0 +1,75 @@
+-- 楼栋、单元、房间基础数据
+-- 原来楼栋、单元、房间只存在于 person 表的楼号、单元、房号字段中，没有住户的房间无法记录
+-- 人员通过 room_id 关联房间；楼号、单元、房号字段保留并与所属房间保持一致（查询、导出、楼栋权限仍使用这些字段）
+
+CREATE TABLE IF NOT EXISTS building (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    building_number VARCHAR(20) NOT NULL COMMENT '楼号',
+    name VARCHAR(100) NULL COMMENT '名称',
+    floor_count INT DEFAULT 0 COMMENT '总层数，0 表示未知',
+    remark VARCHAR(500) NULL COMMENT '备注',
+    is_del TINYINT DEFAULT 0 COMMENT '软删除标记',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_building_number (building_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='楼栋';
+
+CREATE TABLE IF NOT EXISTS unit (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    building_id BIGINT NOT NULL COMMENT '所属楼栋ID',
+    unit_number INT NOT NULL COMMENT '单元号',
+    is_del TINYINT DEFAULT 0 COMMENT '软删除标记',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_building_unit (building_id, unit_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单元';
+
+CREATE TABLE IF NOT EXISTS room (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    building_id BIGINT NOT NULL COMMENT '所属楼栋ID',
+    unit_id BIGINT NOT NULL COMMENT '所属单元ID',
+    room_number VARCHAR(100) NOT NULL COMMENT '房号',
+    floor INT DEFAULT 0 COMMENT '楼层，0 表示未知',
+    area DECIMAL(10,2) DEFAULT 0 COMMENT '建筑面积（平方米）',
+    property_type VARCHAR(50) NULL COMMENT '房屋性质',
+    remark VARCHAR(500) NULL COMMENT '备注',
+    is_del TINYINT DEFAULT 0 COMMENT '软删除标记',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_unit_room (unit_id, room_number),
+    INDEX idx_building_id (building_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='房间';
+
+ALTER TABLE person
+ADD COLUMN room_id BIGINT NULL COMMENT '所属房间ID' AFTER room_number,
+ADD INDEX idx_room_id (room_id);
+
+-- 按已有人员生成楼栋、单元、房间（只取未删除且楼号、单元、房号完整的人员）
+INSERT IGNORE INTO building (building_number)
+SELECT DISTINCT building_number FROM person
+WHERE is_del = 0 AND building_number <> '' AND unit_number > 0 AND room_number <> '';
+
+INSERT IGNORE INTO unit (building_id, unit_number)
+SELECT DISTINCT b.id, p.unit_number
+FROM person p
+JOIN building b ON b.building_number = p.building_number
+WHERE p.is_del = 0 AND p.unit_number > 0 AND p.room_number <> '';
+
+-- 楼层按三、四位数字房号推算（如 301 为3层、1502 为15层），房屋性质取住户填写的住房性质
+INSERT IGNORE INTO room (building_id, unit_id, room_number, floor, property_type)
+SELECT u.building_id, u.id, p.room_number,
+    CASE WHEN p.room_number REGEXP '^[0-9]{3,4}$' THEN FLOOR(p.room_number / 100) ELSE 0 END,
+    MAX(p.property_nature)
+FROM person p
+JOIN building b ON b.building_number = p.building_number
+JOIN unit u ON u.building_id = b.id AND u.unit_number = p.unit_number
+WHERE p.is_del = 0 AND p.room_number <> ''
+GROUP BY u.building_id, u.id, p.room_number;
+
+-- 关联人员与房间（已删除人员的房间存在时同样关联）
+UPDATE person p
+JOIN building b ON b.building_number = p.building_number
+JOIN unit u ON u.building_id = b.id AND u.unit_number = p.unit_number
+JOIN room r ON r.unit_id = u.id AND r.room_number = p.room_number
+SET p.room_id = r.id
+WHERE p.room_id IS NULL;
@@ -0,0 +1,28 @@
+-- Excel 导入批次及变更记录（用于回滚导入）
+
+CREATE TABLE IF NOT EXISTS import_batch (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    file_name VARCHAR(255) NULL COMMENT '文件名',
+    profile VARCHAR(100) NULL COMMENT '导入方案',
+    operator VARCHAR(50) NULL COMMENT '操作人',
+    transactional TINYINT DEFAULT 0 COMMENT '是否整体事务：1是，0否（按工作表事务）',
+    status VARCHAR(20) NOT NULL COMMENT '状态：running/completed/partial/failed/rolled_back',
+    inserted INT DEFAULT 0 COMMENT '新增人数',
+    updated INT DEFAULT 0 COMMENT '更新人数',
+    removed INT DEFAULT 0 COMMENT '软删除人数',
+    rolled_back_at DATETIME NULL COMMENT '回滚时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Excel导入批次';
+
+CREATE TABLE IF NOT EXISTS import_batch_change (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    batch_id BIGINT NOT NULL COMMENT '批次ID',
+    person_id BIGINT NOT NULL COMMENT '人员ID',
+    action VARCHAR(20) NOT NULL COMMENT '变更类型：insert/update/remove',
+    old_values TEXT NULL COMMENT '更新前的字段值（JSON）',
+    new_values TEXT NULL COMMENT '导入写入的字段值（JSON），回滚前用于检查是否已被修改',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_batch_id (batch_id),
+    INDEX idx_person_id (person_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Excel导入批次变更记录';
@@ -0,0 +1,25 @@
+-- Excel 导入任务（异步导入及导入历史）
+
+CREATE TABLE IF NOT EXISTS import_job (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    file_name VARCHAR(255) NULL COMMENT '文件名',
+    file_hash VARCHAR(64) NULL COMMENT '文件SHA-256',
+    file_size BIGINT NULL COMMENT '文件大小（字节）',
+    uploader VARCHAR(50) NULL COMMENT '上传人',
+    uploader_id BIGINT NULL COMMENT '上传人用户ID',
+    profile VARCHAR(100) NULL COMMENT '导入方案',
+    remove_missing TINYINT DEFAULT 0 COMMENT '是否删除表中已不存在的人员',
+    transactional TINYINT DEFAULT 0 COMMENT '是否整体事务',
+    status VARCHAR(20) NOT NULL COMMENT '状态：pending/running/completed/failed',
+    batch_id BIGINT NULL COMMENT '导入批次ID',
+    error TEXT NULL COMMENT '失败原因',
+    progress TEXT NULL COMMENT '各工作表进度（JSON）',
+    result MEDIUMTEXT NULL COMMENT '导入结果（JSON）',
+    started_at DATETIME NULL COMMENT '开始时间',
+    finished_at DATETIME NULL COMMENT '结束时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_uploader_id (uploader_id),
+    INDEX idx_file_hash (file_hash),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Excel导入任务';
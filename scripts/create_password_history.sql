@@ -0,0 +1,9 @@
+-- 用户历史密码（密码策略：不得重复使用最近 N 次的密码）
+
+CREATE TABLE IF NOT EXISTS sys_password_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    password_hash VARCHAR(255) NOT NULL COMMENT '密码（BCrypt加密）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户历史密码';
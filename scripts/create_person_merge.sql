@@ -0,0 +1,15 @@
+-- 重复人员合并记录（被合并人员软删除，电动车转移到保留的人员）
+
+CREATE TABLE IF NOT EXISTS person_merge (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    target_person_id BIGINT NOT NULL COMMENT '保留的人员ID',
+    source_person_id BIGINT NOT NULL COMMENT '被合并（软删除）的人员ID',
+    reason VARCHAR(20) NULL COMMENT '重复原因：id_card/name_phone/name_room',
+    operator VARCHAR(50) NULL COMMENT '操作人',
+    filled_fields VARCHAR(1000) NULL COMMENT '从被合并人员补充到保留人员的字段（逗号分隔）',
+    bicycle_num BIGINT DEFAULT 0 COMMENT '转移的电动车数',
+    source_snapshot MEDIUMTEXT NULL COMMENT '被合并人员合并前的数据（JSON，加密存储）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_target_person_id (target_person_id),
+    INDEX idx_source_person_id (source_person_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='重复人员合并记录';
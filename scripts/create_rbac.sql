@@ -0,0 +1,40 @@
+-- 角色、权限及用户楼栋范围
+-- admin 角色在代码中固定拥有全部权限和全部楼栋，无需配置
+
+CREATE TABLE IF NOT EXISTS sys_role (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(50) NOT NULL COMMENT '角色编码',
+    name VARCHAR(50) NOT NULL COMMENT '角色名称',
+    description VARCHAR(255) NULL COMMENT '描述',
+    all_buildings TINYINT(1) DEFAULT 0 COMMENT '是否可访问全部楼栋：1是，0否（按用户楼栋范围）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色';
+
+CREATE TABLE IF NOT EXISTS sys_role_permission (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    role_code VARCHAR(50) NOT NULL COMMENT '角色编码',
+    permission VARCHAR(100) NOT NULL COMMENT '权限标识',
+    UNIQUE KEY uk_role_permission (role_code, permission)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色权限';
+
+CREATE TABLE IF NOT EXISTS sys_user_building (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    building_number VARCHAR(50) NOT NULL COMMENT '楼号',
+    UNIQUE KEY uk_user_building (user_id, building_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户负责的楼栋';
+
+-- 默认角色：user 保持原有的全部楼栋访问；grid_worker 网格员只能访问分配的楼栋
+INSERT IGNORE INTO sys_role (code, name, description, all_buildings) VALUES
+('admin', '系统管理员', '拥有全部权限', 1),
+('user', '普通用户', '可查看、编辑、导出全部楼栋人员', 1),
+('grid_worker', '网格员', '只能查看和编辑负责楼栋的人员', 0);
+
+INSERT IGNORE INTO sys_role_permission (role_code, permission) VALUES
+('user', 'person:view_sensitive'),
+('user', 'person:export'),
+('user', 'person:edit'),
+('grid_worker', 'person:view_sensitive'),
+('grid_worker', 'person:edit');
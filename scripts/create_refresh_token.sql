@@ -0,0 +1,15 @@
+-- 刷新token（轮换及重用检测）
+
+CREATE TABLE IF NOT EXISTS sys_refresh_token (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    token_hash VARCHAR(64) NOT NULL COMMENT '刷新token的SHA-256',
+    family_id VARCHAR(64) NOT NULL COMMENT '登录会话ID，同一次登录轮换出的刷新token相同',
+    expires_at DATETIME NOT NULL COMMENT '过期时间',
+    used_at DATETIME NULL COMMENT '已轮换时间',
+    revoked_at DATETIME NULL COMMENT '吊销时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_token_hash (token_hash),
+    INDEX idx_user_id (user_id),
+    INDEX idx_family_id (family_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='刷新token';
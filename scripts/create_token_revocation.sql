@@ -0,0 +1,13 @@
+-- token 吊销记录（登出、修改密码、禁用用户、强制下线）
+
+CREATE TABLE IF NOT EXISTS sys_token_revocation (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    token_id VARCHAR(64) NULL COMMENT 'token ID（jti），为空表示吊销用户的全部token',
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    reason VARCHAR(50) NULL COMMENT '吊销原因',
+    revoked_at DATETIME(3) NOT NULL COMMENT '吊销时间',
+    expires_at DATETIME NOT NULL COMMENT '记录失效时间（之后相关token已自然过期，可清理）',
+    INDEX idx_token_id (token_id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='token吊销记录';
@@ -0,0 +1,22 @@
+-- 双因素认证（TOTP）
+
+ALTER TABLE sys_user
+ADD COLUMN totp_secret VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'TOTP密钥（Base32）'
+AFTER password_changed_at;
+
+ALTER TABLE sys_user
+ADD COLUMN totp_enabled TINYINT(1) DEFAULT 0 COMMENT '是否启用双因素认证：1是，0否'
+AFTER totp_secret;
+
+ALTER TABLE sys_user
+ADD COLUMN totp_last_step BIGINT DEFAULT 0 COMMENT '最近一次验证通过的TOTP时间步（防止验证码重放）'
+AFTER totp_enabled;
+
+CREATE TABLE IF NOT EXISTS sys_recovery_code (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    code_hash VARCHAR(64) NOT NULL COMMENT '恢复码的SHA-256',
+    used_at DATETIME NULL COMMENT '使用时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='双因素认证恢复码';
@@ -0,0 +1,25 @@
+-- 登录会话（设备）
+
+CREATE TABLE IF NOT EXISTS sys_user_session (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL COMMENT '会话ID（刷新token的FamilyID）',
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    ip VARCHAR(64) NULL COMMENT '登录IP',
+    user_agent VARCHAR(512) NULL COMMENT '客户端User-Agent',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+    last_seen_at DATETIME NOT NULL COMMENT '最后活跃时间',
+    last_seen_ip VARCHAR(64) NULL COMMENT '最后活跃IP',
+    expires_at DATETIME NOT NULL COMMENT '过期时间（刷新token过期时间）',
+    revoked_at DATETIME NULL COMMENT '吊销时间',
+    UNIQUE KEY uk_session_id (session_id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='登录会话';
+
+-- 吊销单个会话的访问token
+ALTER TABLE sys_token_revocation
+ADD COLUMN session_id VARCHAR(64) NULL COMMENT '会话ID，不为空表示吊销该会话的全部token'
+AFTER token_id;
+
+ALTER TABLE sys_token_revocation
+ADD INDEX idx_session_id (session_id);
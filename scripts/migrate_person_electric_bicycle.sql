@@ -0,0 +1,48 @@
+-- 将 person 表中的车牌号、品牌型号迁移到 electric_bicycle 表
+-- 之后电动车由 electric_bicycle 表维护，person 表的是否有电动车、车牌号、品牌型号按电动车表同步
+-- 可重复执行：已登记的车牌号不会重复插入
+
+-- 车牌号统一格式（去空格、转大写）；多人登记了相同车牌号时只迁移到 ID 最小的人员名下
+INSERT INTO electric_bicycle (person_id, model, plate_number, is_del, created_at, updated_at)
+SELECT p.id,
+       LEFT(COALESCE(NULLIF(TRIM(p.brand_model), ''), '未登记'), 50),
+       src.plate_number,
+       0,
+       NOW(),
+       NOW()
+FROM (
+    SELECT MIN(id) AS person_id,
+           UPPER(REPLACE(TRIM(license_plate), ' ', '')) AS plate_number
+    FROM person
+    WHERE is_del = 0 AND TRIM(IFNULL(license_plate, '')) <> ''
+    GROUP BY UPPER(REPLACE(TRIM(license_plate), ' ', ''))
+) src
+JOIN person p ON p.id = src.person_id
+WHERE CHAR_LENGTH(src.plate_number) <= 20
+  AND NOT EXISTS (
+    SELECT 1 FROM electric_bicycle b
+    WHERE b.is_del = 0 AND b.plate_number = src.plate_number
+  );
+
+-- 按电动车表同步人员的是否有电动车、车牌号、品牌型号（取 ID 最小的电动车）
+UPDATE person p
+JOIN (
+    SELECT person_id, MIN(id) AS bicycle_id
+    FROM electric_bicycle
+    WHERE is_del = 0
+    GROUP BY person_id
+) fb ON fb.person_id = p.id
+JOIN electric_bicycle b ON b.id = fb.bicycle_id
+SET p.has_electric_car = 1,
+    p.license_plate = b.plate_number,
+    p.brand_model = CONCAT_WS(' ', NULLIF(b.brand, ''), b.model);
+
+-- 未能迁移的车牌号（超过20位，或与其他人员重复），需人工处理
+SELECT p.id, p.name, p.building_number, p.unit_number, p.room_number, p.license_plate
+FROM person p
+WHERE p.is_del = 0 AND TRIM(IFNULL(p.license_plate, '')) <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM electric_bicycle b
+    WHERE b.is_del = 0 AND b.person_id = p.id
+      AND b.plate_number = UPPER(REPLACE(TRIM(p.license_plate), ' ', ''))
+  );